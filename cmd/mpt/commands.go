@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/trie"
+)
+
+// inputFlags are the flags of the commands building a trie from a file of key-value pairs.
+type inputFlags struct {
+	in     string
+	format string
+}
+
+func (f *inputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.in, "in", "", "file of key-value pairs, - for stdin")
+	fs.StringVar(&f.format, "format", "", "format of the input: hex, json or csv (default from the file extension, or hex)")
+}
+
+// build builds the trie of the key-value pairs of the input file.
+func (f *inputFlags) build(stdin io.Reader) (*trie.Trie, error) {
+	kvs, err := readPairs(f.in, f.format, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	t := trie.NewTrie()
+	if err := t.PutBatch(kvs); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func runRoot(e *env, args []string) error {
+	fs := newFlagSet(e, "root")
+	var input inputFlags
+	input.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	t, err := input.build(e.stdin)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(e.stdout, hexutil.Encode(t.Hash()))
+	return err
+}
+
+func runDump(e *env, args []string) error {
+	fs := newFlagSet(e, "dump")
+	var input inputFlags
+	input.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	t, err := input.build(e.stdin)
+	if err != nil {
+		return err
+	}
+	return t.ToDOT(e.stdout)
+}
+
+func runProve(e *env, args []string) error {
+	fs := newFlagSet(e, "prove")
+	var input inputFlags
+	input.register(fs)
+	key := fs.String("key", "", "key to prove, in hex, 0x for the empty key")
+	encoding := fs.String("encoding", encodingJSON, "encoding of the proof: json, rlp or hex")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkEncoding(*encoding); err != nil {
+		return err
+	}
+
+	if *key == "" {
+		return errors.New("missing key, set it with -key")
+	}
+	k, err := decodeHex(*key)
+	if err != nil {
+		return fmt.Errorf("invalid key %q: %w", *key, err)
+	}
+
+	t, err := input.build(e.stdin)
+	if err != nil {
+		return err
+	}
+
+	// a proof of absence is written too, if the key is not in the trie
+	p, _, err := t.Prove(k)
+	if err != nil {
+		return err
+	}
+	return writeProof(e.stdout, *encoding, t.Hash(), k, p)
+}
+
+func runVerify(e *env, args []string) error {
+	fs := newFlagSet(e, "verify")
+	root := fs.String("root", "", "root hash to verify the proof against, in hex")
+	key := fs.String("key", "", "key of the proof, in hex (default the key in the proof, required for hex)")
+	proofPath := fs.String("proof", "", "file of the proof, - for stdin")
+	encoding := fs.String("encoding", encodingJSON, "encoding of the proof: json, rlp or hex")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkEncoding(*encoding); err != nil {
+		return err
+	}
+
+	rootHash, err := decodeHex(*root)
+	if err != nil || len(rootHash) != 32 {
+		return fmt.Errorf("invalid root hash %q, expected 32 bytes in hex", *root)
+	}
+	if *proofPath == "" {
+		return errors.New("missing proof file, set it with -proof")
+	}
+
+	r := e.stdin
+	if *proofPath != "-" {
+		f, err := os.Open(*proofPath)
+		if err != nil {
+			return fmt.Errorf("could not open proof: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	proven, p, err := readProof(r, *encoding)
+	if err != nil {
+		return err
+	}
+
+	k := proven
+	if *key != "" {
+		k, err = decodeHex(*key)
+		if err != nil {
+			return fmt.Errorf("invalid key %q: %w", *key, err)
+		}
+		if proven != nil && !bytes.Equal(k, proven) {
+			return fmt.Errorf("the proof is for key %v, not %v", hexutil.Encode(proven), hexutil.Encode(k))
+		}
+	}
+	if k == nil {
+		return errors.New("missing key, set it with -key")
+	}
+
+	value, found, err := proof.VerifyProof(rootHash, k, p)
+	if err != nil {
+		return fmt.Errorf("invalid proof: %w", err)
+	}
+	if !found {
+		_, err = fmt.Fprintln(e.stdout, "absent")
+		return err
+	}
+	_, err = fmt.Fprintln(e.stdout, hexutil.Encode(value))
+	return err
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mpetrun5/merkle-patricia-trie/trie"
+)
+
+// Formats of the files of key-value pairs, whose keys and values are hex encoded,
+// with or without the 0x prefix:
+//   - hex: a pair per line, the key and the value separated by spaces.
+//     Empty lines and lines starting with # are ignored.
+//   - json: an object mapping the keys to the values.
+//   - csv: a pair per row, the key in the first column and the value in the second.
+//     A header row "key,value" is ignored.
+const (
+	formatHex  = "hex"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// formatOf returns the format given by the flag, or guesses it from the file extension.
+func formatOf(path string, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			return formatJSON, nil
+		case ".csv":
+			return formatCSV, nil
+		default:
+			return formatHex, nil
+		}
+	}
+
+	switch format {
+	case formatHex, formatJSON, formatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown input format %q, expected hex, json or csv", format)
+	}
+}
+
+// readPairs reads the key-value pairs from the file, or from stdin if the path is "-".
+func readPairs(path string, format string, stdin io.Reader) ([]trie.KeyValue, error) {
+	if path == "" {
+		return nil, errors.New("missing input file, set it with -in")
+	}
+	format, err := formatOf(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open input: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var kvs []trie.KeyValue
+	switch format {
+	case formatJSON:
+		kvs, err = parseJSON(r)
+	case formatCSV:
+		kvs, err = parseCSV(r)
+	default:
+		kvs, err = parseHex(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %v: %w", path, err)
+	}
+	return kvs, nil
+}
+
+func parseHex(r io.Reader) ([]trie.KeyValue, error) {
+	var kvs []trie.KeyValue
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %v: expected a key and a value, got %v fields", line, len(fields))
+		}
+		kv, err := parsePair(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", line, err)
+		}
+		kvs = append(kvs, kv)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+func parseJSON(r io.Reader) ([]trie.KeyValue, error) {
+	var pairs map[string]string
+	if err := json.NewDecoder(r).Decode(&pairs); err != nil {
+		return nil, err
+	}
+
+	// sort the keys, so that the errors don't depend on the order of the map
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]trie.KeyValue, 0, len(pairs))
+	for _, key := range keys {
+		kv, err := parsePair(key, pairs[key])
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+func parseCSV(r io.Reader) ([]trie.KeyValue, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var kvs []trie.KeyValue
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return kvs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row == 1 && strings.EqualFold(record[0], "key") && strings.EqualFold(record[1], "value") {
+			continue
+		}
+
+		kv, err := parsePair(record[0], record[1])
+		if err != nil {
+			return nil, fmt.Errorf("row %v: %w", row, err)
+		}
+		kvs = append(kvs, kv)
+	}
+}
+
+func parsePair(key string, value string) (trie.KeyValue, error) {
+	k, err := decodeHex(key)
+	if err != nil {
+		return trie.KeyValue{}, fmt.Errorf("invalid key %q: %w", key, err)
+	}
+	v, err := decodeHex(value)
+	if err != nil {
+		return trie.KeyValue{}, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	if len(v) == 0 {
+		return trie.KeyValue{}, fmt.Errorf("empty value for key %q", key)
+	}
+	return trie.KeyValue{Key: k, Value: v}, nil
+}
+
+// decodeHex decodes the hex string, with or without the 0x prefix.
+func decodeHex(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/trie"
+	"github.com/stretchr/testify/require"
+)
+
+var testPairs = []trie.KeyValue{
+	{Key: []byte{0x01}, Value: []byte{0xaa}},
+	{Key: []byte{0x02}, Value: []byte{0xbb, 0xbb}},
+	{Key: []byte{0x02, 0x03}, Value: []byte{0xcc}},
+}
+
+// writeFile writes the content to a file in a temporary directory, and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestReadPairs(t *testing.T) {
+	t.Run("should read the hex format", func(t *testing.T) {
+		path := writeFile(t, "pairs.txt", "# comment\n0x01 0xaa\n\n02   bbbb\n0x0203\t0xcc\n")
+		kvs, err := readPairs(path, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, testPairs, kvs)
+	})
+
+	t.Run("should read the csv format", func(t *testing.T) {
+		path := writeFile(t, "pairs.csv", "key,value\n0x01,0xaa\n02, bbbb\n0x0203,0xcc\n")
+		kvs, err := readPairs(path, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, testPairs, kvs)
+	})
+
+	t.Run("should read the json format", func(t *testing.T) {
+		path := writeFile(t, "pairs.json", `{"0x0203": "0xcc", "0x01": "0xaa", "0x02": "bbbb"}`)
+		kvs, err := readPairs(path, "", nil)
+		require.NoError(t, err)
+		require.Equal(t, testPairs, kvs)
+	})
+
+	t.Run("should read the format given by the flag from stdin", func(t *testing.T) {
+		kvs, err := readPairs("-", formatCSV, strings.NewReader("0x01,0xaa\n02,bbbb\n0x0203,0xcc\n"))
+		require.NoError(t, err)
+		require.Equal(t, testPairs, kvs)
+	})
+
+	t.Run("should fail on invalid input", func(t *testing.T) {
+		for _, c := range []struct {
+			format string
+			input  string
+			err    string
+		}{
+			{formatHex, "0x01\n", "line 1: expected a key and a value"},
+			{formatHex, "0x01 0xaa\n0x0g 0xaa\n", "line 2: invalid key"},
+			{formatHex, "0x01 0x\n", "empty value"},
+			{formatCSV, "0x01,0xaa,0xbb\n", "wrong number of fields"},
+			{formatJSON, `["0x01"]`, "cannot unmarshal"},
+			{"xml", "", "unknown input format"},
+		} {
+			_, err := readPairs("-", c.format, strings.NewReader(c.input))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.err)
+		}
+
+		_, err := readPairs("", "", nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,114 @@
+// Command mpt builds a Merkle Patricia Trie from key-value pairs in a file,
+// and prints its root hash, its structure, or the proof of a key. It also
+// verifies proofs against a root hash.
+//
+// Usage:
+//
+//	mpt root -in pairs.csv
+//	mpt dump -in pairs.json | dot -Tpng -o trie.png
+//	mpt prove -in pairs.txt -key 0x0102 -encoding json > proof.json
+//	mpt verify -root 0x... -key 0x0102 -proof proof.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// errUsage is returned when the arguments are invalid, the usage has already been printed.
+var errUsage = errors.New("invalid arguments")
+
+// env is the standard streams of the commands.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"root":   {"print the root hash of the trie of the key-value pairs", runRoot},
+	"dump":   {"print the nodes of the trie as a Graphviz DOT graph", runDump},
+	"prove":  {"print the proof of a key in the trie", runProve},
+	"verify": {"verify the proof of a key against a root hash", runVerify},
+}
+
+func main() {
+	if err := run(&env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "mpt: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(e *env, args []string) error {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return errUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+			usage(e.stdout)
+			return nil
+		}
+		fmt.Fprintf(e.stderr, "mpt: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return errUsage
+	}
+	err := cmd.run(e, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: mpt <command> [flags]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8v %v\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(w, "\nRun 'mpt <command> -h' for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set for the command, which doesn't exit on errors.
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("mpt "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseFlags parses the arguments, and converts the errors to errUsage since
+// the flag set already printed them with the usage. flag.ErrHelp is returned as is,
+// so that the command stops without failing.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runCommand runs the command with the arguments, and returns what it printed.
+func runCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := run(&env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+	return stdout.String(), err
+}
+
+func TestRun(t *testing.T) {
+	pairs := writeFile(t, "pairs.csv", "0x01,0xaa\n02,bbbb\n0x0203,0xcc\n")
+	const root = "0xea1dd630732d9a43998a366501ca831c36dd5b0f7826725a234cd13337785447\n"
+
+	t.Run("should print the root hash", func(t *testing.T) {
+		out, err := runCommand(t, "", "root", "-in", pairs)
+		require.NoError(t, err)
+		require.Equal(t, root, out)
+
+		out, err = runCommand(t, "0x01 0xaa\n02 bbbb\n0x0203 0xcc\n", "root", "-in", "-")
+		require.NoError(t, err)
+		require.Equal(t, root, out)
+	})
+
+	t.Run("should dump the trie", func(t *testing.T) {
+		out, err := runCommand(t, "", "dump", "-in", pairs)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(out, "digraph trie {\n"), out)
+	})
+
+	t.Run("should verify the proof of a key", func(t *testing.T) {
+		for _, encoding := range []string{encodingJSON, encodingRLP, encodingHex} {
+			proof, err := runCommand(t, "", "prove", "-in", pairs, "-key", "0x0203", "-encoding", encoding)
+			require.NoError(t, err)
+
+			out, err := runCommand(t, proof, "verify", "-root", strings.TrimSpace(root), "-key", "0x0203", "-proof", "-", "-encoding", encoding)
+			require.NoError(t, err)
+			require.Equal(t, "0xcc\n", out)
+		}
+	})
+
+	t.Run("should verify the proof of absence of a key", func(t *testing.T) {
+		proof, err := runCommand(t, "", "prove", "-in", pairs, "-key", "0x05")
+		require.NoError(t, err)
+
+		out, err := runCommand(t, proof, "verify", "-root", strings.TrimSpace(root), "-proof", "-")
+		require.NoError(t, err)
+		require.Equal(t, "absent\n", out)
+	})
+
+	t.Run("should fail if the proof doesn't match", func(t *testing.T) {
+		proof, err := runCommand(t, "", "prove", "-in", pairs, "-key", "0x0203")
+		require.NoError(t, err)
+
+		_, err = runCommand(t, proof, "verify", "-root", strings.TrimSpace(root), "-key", "0x01", "-proof", "-")
+		require.Error(t, err)
+
+		otherRoot := "0x" + strings.Repeat("00", 32)
+		_, err = runCommand(t, proof, "verify", "-root", otherRoot, "-proof", "-")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid proof")
+	})
+
+	t.Run("should fail on invalid arguments", func(t *testing.T) {
+		_, err := runCommand(t, "")
+		require.True(t, errors.Is(err, errUsage), err)
+
+		_, err = runCommand(t, "", "build")
+		require.True(t, errors.Is(err, errUsage), err)
+
+		_, err = runCommand(t, "", "root", "-in")
+		require.True(t, errors.Is(err, errUsage), err)
+
+		_, err = runCommand(t, "", "prove", "-in", pairs)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+)
+
+// Encodings of a proof, the nodes are ordered from the root node along the path of the key:
+//   - json: an object with the key and the list of nodes, like the storage proofs of eth_getProof.
+//   - rlp: the canonical encoding of proof.Encode, in hex on a single line.
+//   - hex: a node per line, the key is not included.
+const (
+	encodingJSON = "json"
+	encodingRLP  = "rlp"
+	encodingHex  = "hex"
+)
+
+type jsonProof struct {
+	Key   hexutil.Bytes   `json:"key"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+func checkEncoding(encoding string) error {
+	switch encoding {
+	case encodingJSON, encodingRLP, encodingHex:
+		return nil
+	default:
+		return fmt.Errorf("unknown proof encoding %q, expected json, rlp or hex", encoding)
+	}
+}
+
+// writeProof writes the nodes of the proof on the path of the key.
+func writeProof(w io.Writer, encoding string, rootHash []byte, key []byte, p proof.Proof) error {
+	encoded, err := proof.Encode(rootHash, key, p)
+	if err != nil {
+		return err
+	}
+
+	if encoding == encodingRLP {
+		_, err := fmt.Fprintln(w, hexutil.Encode(encoded))
+		return err
+	}
+
+	// the decoded proof keeps the nodes in the order of the path
+	_, decoded, err := proof.Decode(encoded)
+	if err != nil {
+		return err
+	}
+	nodes := decoded.Serialize()
+
+	if encoding == encodingHex {
+		for _, n := range nodes {
+			if _, err := fmt.Fprintln(w, hexutil.Encode(n)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	out := jsonProof{Key: key, Proof: make([]hexutil.Bytes, len(nodes))}
+	for i, n := range nodes {
+		out.Proof[i] = n
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// readProof reads a proof written by writeProof, and returns the key of the proof,
+// which is nil for the hex encoding.
+func readProof(r io.Reader, encoding string) ([]byte, proof.Proof, error) {
+	if encoding == encodingJSON {
+		var in jsonProof
+		if err := json.NewDecoder(r).Decode(&in); err != nil {
+			return nil, nil, fmt.Errorf("could not decode proof: %w", err)
+		}
+		return in.Key, proofOf(in.Proof), nil
+	}
+
+	var nodes []hexutil.Bytes
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		n, err := decodeHex(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %v: invalid node: %w", line, err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read proof: %w", err)
+	}
+
+	if encoding == encodingHex {
+		return nil, proofOf(nodes), nil
+	}
+
+	if len(nodes) != 1 {
+		return nil, nil, fmt.Errorf("expected the encoded proof on a single line, got %v lines", len(nodes))
+	}
+	key, p, err := proof.Decode(nodes[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, p, nil
+}
+
+// proofOf creates a proof from the list of nodes, keyed by their hashes.
+func proofOf(nodes []hexutil.Bytes) proof.Proof {
+	p := proof.NewProofDB()
+	for _, n := range nodes {
+		p.Put(crypto.Keccak256(n), n)
+	}
+	return p
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/trie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadProof(t *testing.T) {
+	tr := trie.NewTrie()
+	require.NoError(t, tr.PutBatch(testPairs))
+	key := []byte{0x02, 0x03}
+	p, found, err := tr.Prove(key)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	for _, encoding := range []string{encodingJSON, encodingRLP, encodingHex} {
+		t.Run("should read the proof written with the "+encoding+" encoding", func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writeProof(&buf, encoding, tr.Hash(), key, p))
+
+			proven, read, err := readProof(&buf, encoding)
+			require.NoError(t, err)
+			if encoding == encodingHex {
+				require.Nil(t, proven)
+			} else {
+				require.Equal(t, key, proven)
+			}
+
+			value, found, err := proof.VerifyProof(tr.Hash(), key, read)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, []byte{0xcc}, value)
+		})
+	}
+
+	t.Run("should not write an invalid proof", func(t *testing.T) {
+		err := writeProof(&bytes.Buffer{}, encodingJSON, tr.Hash(), key, proof.NewProofDB())
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
@@ -1,9 +1,18 @@
 package nibble
 
 import (
+	"errors"
 	"fmt"
 )
 
+var (
+	// ErrOddNibbles is returned when converting an odd number of nibbles to bytes.
+	ErrOddNibbles = errors.New("odd number of nibbles")
+
+	// ErrInvalidPrefixed is returned when decoding bytes which are not hex-prefix encoded.
+	ErrInvalidPrefixed = errors.New("invalid hex-prefix encoding")
+)
+
 type Nibble byte
 
 func IsNibble(nibble byte) bool {
@@ -40,9 +49,10 @@ func FromByte(b byte) []Nibble {
 }
 
 func FromBytes(bs []byte) []Nibble {
-	ns := make([]Nibble, 0, len(bs)*2)
-	for _, b := range bs {
-		ns = append(ns, FromByte(b)...)
+	ns := make([]Nibble, len(bs)*2)
+	for i, b := range bs {
+		ns[i*2] = Nibble(b >> 4)
+		ns[i*2+1] = Nibble(b % 16)
 	}
 	return ns
 }
@@ -77,17 +87,40 @@ func ToPrefixed(ns []Nibble, isLeafNode bool) []Nibble {
 	return prefixed
 }
 
-// ToBytes converts a slice of nibbles to a byte slice
-// assuming the nibble slice has even number of nibbles.
-func ToBytes(ns []Nibble) []byte {
-	buf := make([]byte, 0, len(ns)/2)
+// ToPrefixedBytes converts a slice of nibbles to its hex-prefix encoding,
+// which always has an even number of nibbles.
+func ToPrefixedBytes(ns []Nibble, isLeafNode bool) []byte {
+	prefixed, _ := ToBytes(ToPrefixed(ns, isLeafNode))
+	return prefixed
+}
+
+// FromPrefixedBytes decodes a hex-prefix encoded path, it's the inverse of
+// ToPrefixedBytes(path, isLeaf). The flag nibble tells whether the path is the path
+// of a leaf node and whether it has an odd number of nibbles, in which case the second
+// nibble is part of the path, otherwise it's padding and must be 0.
+// An error wrapping ErrInvalidPrefixed is returned if b is malformed.
+func FromPrefixedBytes(b []byte) (path []Nibble, isLeaf bool, err error) {
+	p, isLeaf, err := PathFromPrefixedBytes(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return p.Nibbles(), isLeaf, nil
+}
 
+// ToBytes converts a slice of nibbles to a byte slice,
+// ErrOddNibbles is returned if the nibble slice has an odd number of nibbles.
+func ToBytes(ns []Nibble) ([]byte, error) {
+	if len(ns)%2 != 0 {
+		return nil, fmt.Errorf("%w: %v", ErrOddNibbles, len(ns))
+	}
+
+	buf := make([]byte, 0, len(ns)/2)
 	for i := 0; i < len(ns); i += 2 {
 		b := byte(ns[i]<<4) + byte(ns[i+1])
 		buf = append(buf, b)
 	}
 
-	return buf
+	return buf, nil
 }
 
 // [0,1,2,3], [0,1,2] => 3
@@ -106,3 +139,23 @@ func PrefixMatchedLen(node1 []Nibble, node2 []Nibble) int {
 
 	return matched
 }
+
+// Compare compares two slices of nibbles lexicographically, a slice is
+// less than any longer slice it's a prefix of.
+// The result is 0 if a == b, -1 if a < b, and +1 if a > b.
+func Compare(a []Nibble, b []Nibble) int {
+	matched := PrefixMatchedLen(a, b)
+	if matched == len(a) && matched == len(b) {
+		return 0
+	}
+	if matched == len(a) {
+		return -1
+	}
+	if matched == len(b) {
+		return 1
+	}
+	if a[matched] < b[matched] {
+		return -1
+	}
+	return 1
+}
@@ -1,6 +1,7 @@
 package nibble
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -80,7 +81,44 @@ func TestFromBytes(t *testing.T) {
 
 func TestToBytes(t *testing.T) {
 	bytes := []byte{0, 1, 2, 3}
-	require.Equal(t, bytes, ToBytes(FromBytes(bytes)))
+	converted, err := ToBytes(FromBytes(bytes))
+	require.NoError(t, err)
+	require.Equal(t, bytes, converted)
+
+	_, err = ToBytes([]Nibble{0, 1, 2})
+	require.True(t, errors.Is(err, ErrOddNibbles), err)
+}
+
+func TestToPrefixedBytes(t *testing.T) {
+	require.Equal(t, []byte{0x11}, ToPrefixedBytes([]Nibble{1}, false))
+	require.Equal(t, []byte{0x20, 0x12}, ToPrefixedBytes([]Nibble{1, 2}, true))
+	require.Equal(t, []byte{0x35, 0x06}, ToPrefixedBytes([]Nibble{5, 0, 6}, true))
+}
+
+func TestFromPrefixedBytes(t *testing.T) {
+	t.Run("should decode the paths encoded by ToPrefixedBytes", func(t *testing.T) {
+		for _, path := range [][]Nibble{{}, {1}, {1, 2}, {5, 0, 6}, {0, 0, 0, 0}, {0xf, 0, 0xf}} {
+			for _, isLeaf := range []bool{false, true} {
+				decoded, leaf, err := FromPrefixedBytes(ToPrefixedBytes(path, isLeaf))
+				require.NoError(t, err)
+				require.Equal(t, path, decoded)
+				require.Equal(t, isLeaf, leaf)
+			}
+		}
+	})
+
+	t.Run("should reject malformed encodings", func(t *testing.T) {
+		for _, b := range [][]byte{
+			{},
+			{0x40},
+			{0xf1, 0x23},
+			{0x01, 0x23},
+			{0x2f},
+		} {
+			_, _, err := FromPrefixedBytes(b)
+			require.True(t, errors.Is(err, ErrInvalidPrefixed), "%x: %v", b, err)
+		}
+	})
 }
 
 func TestPrefixMatchedLen(t *testing.T) {
@@ -88,3 +126,12 @@ func TestPrefixMatchedLen(t *testing.T) {
 	require.Equal(t, 4, PrefixMatchedLen([]Nibble{0, 1, 2, 3}, []Nibble{0, 1, 2, 3}))
 	require.Equal(t, 4, PrefixMatchedLen([]Nibble{0, 1, 2, 3}, []Nibble{0, 1, 2, 3, 4}))
 }
+
+func TestCompare(t *testing.T) {
+	require.Equal(t, 0, Compare([]Nibble{}, []Nibble{}))
+	require.Equal(t, 0, Compare([]Nibble{0, 1, 2}, []Nibble{0, 1, 2}))
+	require.Equal(t, -1, Compare([]Nibble{0, 1}, []Nibble{0, 1, 2}))
+	require.Equal(t, 1, Compare([]Nibble{0, 1, 2}, []Nibble{0, 1}))
+	require.Equal(t, -1, Compare([]Nibble{0, 1, 3}, []Nibble{0, 2}))
+	require.Equal(t, 1, Compare([]Nibble{0, 2}, []Nibble{0, 1, 3}))
+}
@@ -0,0 +1,269 @@
+package nibble
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is an immutable sequence of nibbles packed two per byte, which takes half the
+// memory of a slice of nibbles. Slicing a path shares its bytes, so a path which doesn't
+// start or end on a byte boundary skips the first or the last half byte.
+// The zero value is the empty path.
+type Path struct {
+	// data holds the nibbles, the first one in the high half of the first byte.
+	data []byte
+	// skipFirst tells the high half of the first byte is not part of the path.
+	skipFirst bool
+	// skipLast tells the low half of the last byte is not part of the path.
+	skipLast bool
+}
+
+// NewPath packs the nibbles into a path.
+func NewPath(ns []Nibble) Path {
+	if len(ns) == 0 {
+		return Path{}
+	}
+
+	data := make([]byte, (len(ns)+1)/2)
+	for i, n := range ns {
+		if i%2 == 0 {
+			data[i/2] = byte(n) << 4
+		} else {
+			data[i/2] |= byte(n)
+		}
+	}
+	return Path{data: data, skipLast: len(ns)%2 == 1}
+}
+
+// PathFromBytes returns the path of the nibbles of the bytes, such as the path of a key.
+func PathFromBytes(bs []byte) Path {
+	if len(bs) == 0 {
+		return Path{}
+	}
+
+	data := make([]byte, len(bs))
+	copy(data, bs)
+	return Path{data: data}
+}
+
+// PathFromPrefixedBytes decodes a hex-prefix encoded path like FromPrefixedBytes,
+// without unpacking the nibbles.
+func PathFromPrefixedBytes(b []byte) (Path, bool, error) {
+	if len(b) == 0 {
+		return Path{}, false, fmt.Errorf("%w: empty path", ErrInvalidPrefixed)
+	}
+
+	flag := b[0] >> 4
+	if flag > 3 {
+		return Path{}, false, fmt.Errorf("%w: invalid flag %v", ErrInvalidPrefixed, flag)
+	}
+
+	isLeaf := flag >= 2
+	// odd number of nibbles, the second nibble is part of the path
+	if flag%2 == 1 {
+		path := PathFromBytes(b)
+		path.skipFirst = true
+		return path, isLeaf, nil
+	}
+
+	// even number of nibbles, the second nibble is padding
+	if padding := b[0] & 0x0f; padding != 0 {
+		return Path{}, false, fmt.Errorf("%w: invalid padding %v", ErrInvalidPrefixed, padding)
+	}
+	return PathFromBytes(b[1:]), isLeaf, nil
+}
+
+// Len returns the number of nibbles of the path.
+func (p Path) Len() int {
+	length := len(p.data) * 2
+	if p.skipFirst {
+		length--
+	}
+	if p.skipLast {
+		length--
+	}
+	return length
+}
+
+// At returns the i-th nibble of the path.
+func (p Path) At(i int) Nibble {
+	if i < 0 || i >= p.Len() {
+		panic("nibble: path index out of range")
+	}
+	if p.skipFirst {
+		i++
+	}
+	if i%2 == 0 {
+		return Nibble(p.data[i/2] >> 4)
+	}
+	return Nibble(p.data[i/2] & 0x0f)
+}
+
+// Slice returns the path of the nibbles from start to end, excluded, sharing the bytes of p.
+func (p Path) Slice(start, end int) Path {
+	if start < 0 || end < start || end > p.Len() {
+		panic("nibble: path slice out of range")
+	}
+	if start == end {
+		return Path{}
+	}
+
+	// the positions of the nibbles in data
+	if p.skipFirst {
+		start, end = start+1, end+1
+	}
+	return Path{
+		data:      p.data[start/2 : (end+1)/2],
+		skipFirst: start%2 == 1,
+		skipLast:  end%2 == 1,
+	}
+}
+
+// Concat returns the path of the nibbles of p followed by the nibbles of q.
+func (p Path) Concat(q Path) Path {
+	if p.Len() == 0 {
+		return q
+	}
+	if q.Len() == 0 {
+		return p
+	}
+
+	// the halves of the bytes line up, the bytes only need to be copied
+	if !p.skipLast && !q.skipFirst {
+		data := make([]byte, len(p.data)+len(q.data))
+		copy(data, p.data)
+		copy(data[len(p.data):], q.data)
+		return Path{data: data, skipFirst: p.skipFirst, skipLast: q.skipLast}
+	}
+
+	ns := make([]Nibble, 0, p.Len()+q.Len())
+	return NewPath(q.AppendTo(p.AppendTo(ns)))
+}
+
+// Nibbles unpacks the nibbles of the path.
+func (p Path) Nibbles() []Nibble {
+	return p.AppendTo(make([]Nibble, 0, p.Len()))
+}
+
+// AppendTo appends the nibbles of the path to ns, and returns the extended slice.
+func (p Path) AppendTo(ns []Nibble) []Nibble {
+	for i := 0; i < p.Len(); i++ {
+		ns = append(ns, p.At(i))
+	}
+	return ns
+}
+
+// PrefixMatchedLen returns the number of leading nibbles that the path and ns have in common.
+func (p Path) PrefixMatchedLen(ns []Nibble) int {
+	length := p.Len()
+	matched := 0
+	for matched < length && matched < len(ns) && p.At(matched) == ns[matched] {
+		matched++
+	}
+	return matched
+}
+
+// MatchedLen returns the number of leading nibbles that both paths have in common.
+func (p Path) MatchedLen(q Path) int {
+	length := p.Len()
+	if q.Len() < length {
+		length = q.Len()
+	}
+
+	matched := 0
+	// when the halves of the bytes line up, compare whole bytes first, two nibbles at a time
+	if p.skipFirst == q.skipFirst && length > 0 {
+		i := 0
+		if p.skipFirst {
+			if p.data[0]&0x0f != q.data[0]&0x0f {
+				return 0
+			}
+			i, matched = 1, 1
+		}
+		for matched+2 <= length && p.data[i] == q.data[i] {
+			i, matched = i+1, matched+2
+		}
+	}
+
+	for matched < length && p.At(matched) == q.At(matched) {
+		matched++
+	}
+	return matched
+}
+
+// Compare compares two paths lexicographically, like Compare for slices of nibbles.
+// The result is 0 if p == q, -1 if p < q, and +1 if p > q.
+func (p Path) Compare(q Path) int {
+	matched := p.MatchedLen(q)
+	if matched == p.Len() && matched == q.Len() {
+		return 0
+	}
+	if matched == p.Len() {
+		return -1
+	}
+	if matched == q.Len() {
+		return 1
+	}
+	if p.At(matched) < q.At(matched) {
+		return -1
+	}
+	return 1
+}
+
+// Equal returns whether both paths have the same nibbles.
+func (p Path) Equal(q Path) bool {
+	return p.Len() == q.Len() && p.MatchedLen(q) == p.Len()
+}
+
+// ToPrefixedBytes returns the hex-prefix encoding of the path,
+// the same as ToPrefixedBytes(p.Nibbles(), isLeafNode).
+func (p Path) ToPrefixedBytes(isLeafNode bool) []byte {
+	var flag byte
+	if isLeafNode {
+		flag = 2
+	}
+
+	odd := p.Len()%2 == 1
+	if odd {
+		flag++
+	}
+
+	// the flag and the padding take a byte, followed by the bytes of the path
+	if !odd && !p.skipFirst {
+		prefixed := make([]byte, 1+len(p.data))
+		prefixed[0] = flag << 4
+		copy(prefixed[1:], p.data)
+		return prefixed
+	}
+
+	// the flag takes the half byte which is skipped
+	if odd && p.skipFirst {
+		prefixed := make([]byte, len(p.data))
+		copy(prefixed, p.data)
+		prefixed[0] = flag<<4 | prefixed[0]&0x0f
+		return prefixed
+	}
+
+	// otherwise the nibbles have to be shifted by half a byte
+	prefixed := make([]byte, 0, p.Len()/2+1)
+	i := 0
+	if odd {
+		prefixed = append(prefixed, flag<<4|byte(p.At(0)))
+		i = 1
+	} else {
+		prefixed = append(prefixed, flag<<4)
+	}
+	for ; i < p.Len(); i += 2 {
+		prefixed = append(prefixed, byte(p.At(i))<<4|byte(p.At(i+1)))
+	}
+	return prefixed
+}
+
+// String returns the nibbles of the path in hex.
+func (p Path) String() string {
+	var b strings.Builder
+	for i := 0; i < p.Len(); i++ {
+		b.WriteByte("0123456789abcdef"[p.At(i)])
+	}
+	return b.String()
+}
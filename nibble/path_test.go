@@ -0,0 +1,176 @@
+package nibble
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomNibbles(rnd *rand.Rand, n int) []Nibble {
+	ns := make([]Nibble, n)
+	for i := range ns {
+		ns[i] = Nibble(rnd.Intn(16))
+	}
+	return ns
+}
+
+func TestPath(t *testing.T) {
+	t.Run("should pack the nibbles", func(t *testing.T) {
+		for n := 0; n < 8; n++ {
+			ns := randomNibbles(rand.New(rand.NewSource(int64(n))), n)
+			p := NewPath(ns)
+			require.Equal(t, n, p.Len())
+			for i, nibble := range ns {
+				require.Equal(t, nibble, p.At(i))
+			}
+			if n > 0 {
+				require.Equal(t, ns, p.Nibbles())
+			}
+		}
+
+		require.Equal(t, FromBytes([]byte{0x12, 0xab}), PathFromBytes([]byte{0x12, 0xab}).Nibbles())
+		require.Equal(t, Path{}, NewPath([]Nibble{}))
+		require.Equal(t, Path{}, PathFromBytes(nil))
+	})
+
+	t.Run("should slice the path", func(t *testing.T) {
+		ns := []Nibble{1, 2, 3, 4, 5, 6, 7}
+		p := NewPath(ns)
+		for start := 0; start <= len(ns); start++ {
+			for end := start; end <= len(ns); end++ {
+				sliced := p.Slice(start, end).Slice(0, end-start)
+				require.Equal(t, end-start, sliced.Len())
+				require.Equal(t, ns[start:end], sliced.AppendTo([]Nibble{}), "%v:%v", start, end)
+				require.True(t, NewPath(ns[start:end]).Equal(sliced), "%v:%v", start, end)
+			}
+		}
+
+		require.Panics(t, func() { p.Slice(2, 8) })
+		require.Panics(t, func() { p.At(7) })
+	})
+
+	t.Run("should concat paths", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		for a := 0; a < 5; a++ {
+			for b := 0; b < 5; b++ {
+				first, second := randomNibbles(rnd, a), randomNibbles(rnd, b)
+				joined := append(append([]Nibble{}, first...), second...)
+				require.Equal(t, NewPath(joined), NewPath(first).Concat(NewPath(second)))
+
+				// paths which don't start or end on a byte boundary
+				padded := append(append([]Nibble{0xf}, joined...), 0xf)
+				p := NewPath(padded)
+				concat := p.Slice(1, 1+a).Concat(p.Slice(1+a, 1+a+b))
+				require.Equal(t, joined, concat.AppendTo([]Nibble{}))
+			}
+		}
+	})
+
+	t.Run("should match prefixes and compare like slices of nibbles", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(2))
+		for i := 0; i < 1000; i++ {
+			// few distinct nibbles, so that the paths often share a prefix
+			a, b := randomNibbles(rnd, rnd.Intn(6)), randomNibbles(rnd, rnd.Intn(6))
+			for j := range a {
+				a[j] %= 2
+			}
+			for j := range b {
+				b[j] %= 2
+			}
+
+			pa, pb := NewPath(a), NewPath(b)
+			// the same paths, not starting on a byte boundary
+			shifted := NewPath(append([]Nibble{1}, b...)).Slice(1, len(b)+1)
+			require.Equal(t, PrefixMatchedLen(a, b), pa.PrefixMatchedLen(b), "%v %v", a, b)
+			require.Equal(t, PrefixMatchedLen(a, b), pa.MatchedLen(pb), "%v %v", a, b)
+			require.Equal(t, Compare(a, b), pa.Compare(pb), "%v %v", a, b)
+			require.Equal(t, Compare(a, b) == 0, pa.Equal(pb), "%v %v", a, b)
+			require.Equal(t, PrefixMatchedLen(a, b), pa.MatchedLen(shifted), "%v %v", a, b)
+			require.Equal(t, Compare(a, b), shifted.Compare(pa)*-1, "%v %v", a, b)
+			require.Equal(t, PrefixMatchedLen(a, b), pa.Slice(0, len(a)).MatchedLen(pb.Slice(0, len(b))), "%v %v", a, b)
+		}
+
+		// the padding of an odd path is not a nibble of the path
+		require.Equal(t, 1, NewPath([]Nibble{1}).MatchedLen(NewPath([]Nibble{1, 0})))
+		require.Equal(t, -1, NewPath([]Nibble{1}).Compare(NewPath([]Nibble{1, 0})))
+	})
+
+	t.Run("should hex-prefix encode like the nibbles", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(3))
+		for n := 0; n < 8; n++ {
+			ns := randomNibbles(rnd, n)
+			for _, isLeaf := range []bool{false, true} {
+				prefixed := NewPath(ns).ToPrefixedBytes(isLeaf)
+				require.Equal(t, ToPrefixedBytes(ns, isLeaf), prefixed)
+
+				decoded, leaf, err := PathFromPrefixedBytes(prefixed)
+				require.NoError(t, err)
+				require.True(t, NewPath(ns).Equal(decoded))
+				require.Equal(t, isLeaf, leaf)
+
+				// paths which don't start on a byte boundary
+				shifted := NewPath(append([]Nibble{0xf}, ns...)).Slice(1, n+1)
+				require.Equal(t, prefixed, shifted.ToPrefixedBytes(isLeaf))
+			}
+		}
+
+		_, _, err := PathFromPrefixedBytes([]byte{0x01, 0x23})
+		require.True(t, errors.Is(err, ErrInvalidPrefixed), err)
+	})
+
+	t.Run("should print the nibbles in hex", func(t *testing.T) {
+		require.Equal(t, "0a1f3", NewPath([]Nibble{0, 0xa, 1, 0xf, 3}).String())
+		require.Equal(t, "", Path{}.String())
+	})
+}
+
+var key = []byte{
+	0x29, 0x0d, 0xec, 0xd9, 0x54, 0x8b, 0x62, 0xa8, 0xd6, 0x03, 0x45, 0xa9, 0x88, 0x38, 0x6f, 0xc8,
+	0x4b, 0xa6, 0xbc, 0x95, 0x48, 0x40, 0x08, 0xf6, 0x36, 0x2f, 0x93, 0x16, 0x0e, 0xf3, 0xe5, 0x63,
+}
+
+// the sinks keep the results of the benchmarks, so that they are not optimized away.
+var (
+	nibblesSink []Nibble
+	pathSink    Path
+	bytesSink   []byte
+)
+
+// BenchmarkFromBytes compares the allocations of unpacking the nibbles of a key with packing them.
+func BenchmarkFromBytes(b *testing.B) {
+	b.Run("nibbles", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			nibblesSink = FromBytes(key)
+		}
+	})
+
+	b.Run("path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pathSink = PathFromBytes(key)
+		}
+	})
+}
+
+// BenchmarkToPrefixedBytes compares the allocations of encoding the path of a leaf node.
+func BenchmarkToPrefixedBytes(b *testing.B) {
+	ns := FromBytes(key)[1:]
+	p := PathFromBytes(key).Slice(1, len(key)*2)
+
+	b.Run("nibbles", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bytesSink = ToPrefixedBytes(ns, true)
+		}
+	})
+
+	b.Run("path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bytesSink = p.ToPrefixedBytes(true)
+		}
+	})
+}
@@ -1,13 +1,13 @@
 package node
 
 import (
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mpetrun5/merkle-patricia-trie/nibble"
 )
 
 type BranchNode struct {
 	Branches [16]Node
 	Value    []byte
+	cache    cache
 }
 
 func NewBranchNode() *BranchNode {
@@ -16,54 +16,61 @@ func NewBranchNode() *BranchNode {
 	}
 }
 
-func (b BranchNode) Hash() []byte {
-	return crypto.Keccak256(b.Serialize())
+// Copy returns a copy of the branch node sharing its children, which can be updated
+// without affecting the original node.
+func (b *BranchNode) Copy() *BranchNode {
+	return &BranchNode{
+		Branches: b.Branches,
+		Value:    b.Value,
+	}
+}
+
+func (b *BranchNode) Hash() []byte {
+	return b.cache.hashOf(b, Keccak256)
 }
 
 func (b *BranchNode) SetBranch(nibble nibble.Nibble, node Node) {
 	b.Branches[int(nibble)] = node
+	b.cache.reset()
 }
 
 func (b *BranchNode) RemoveBranch(nibble nibble.Nibble) {
 	b.Branches[int(nibble)] = nil
+	b.cache.reset()
 }
 
 func (b *BranchNode) SetValue(value []byte) {
 	b.Value = value
+	b.cache.reset()
 }
 
 func (b *BranchNode) RemoveValue() {
 	b.Value = nil
+	b.cache.reset()
 }
 
-func (b BranchNode) Raw() []interface{} {
+func (b *BranchNode) Raw() []interface{} {
+	return b.rawWith(Keccak256)
+}
+
+func (b *BranchNode) rawWith(h Hasher) []interface{} {
 	hashes := make([]interface{}, 17)
 	for i := 0; i < 16; i++ {
-		if b.Branches[i] == nil {
-			hashes[i] = EmptyNodeRaw
-		} else {
-			node := b.Branches[i]
-			if len(Serialize(node)) >= 32 {
-				hashes[i] = node.Hash()
-			} else {
-				// if node can be serialized to less than 32 bits, then
-				// use Serialized directly.
-				// it has to be ">=", rather than ">",
-				// so that when deserialized, the content can be distinguished
-				// by length
-				hashes[i] = node.Raw()
-			}
-		}
+		hashes[i] = rawChild(h, b.Branches[i])
 	}
 
 	hashes[16] = b.Value
 	return hashes
 }
 
-func (b BranchNode) Serialize() []byte {
-	return Serialize(b)
+func (b *BranchNode) Serialize() ([]byte, error) {
+	return b.cache.serialize(b, Keccak256)
 }
 
-func (b BranchNode) HasValue() bool {
+func (b *BranchNode) HasValue() bool {
 	return b.Value != nil
 }
+
+func (b *BranchNode) nodeCache() *cache {
+	return &b.cache
+}
@@ -0,0 +1,116 @@
+package node
+
+import (
+	"sync/atomic"
+)
+
+// cache memoizes the serialization and hash of a node, so that they are only
+// computed again after the node was updated, or with another hasher.
+type cache struct {
+	// memo holds a *memo, it's stored atomically so that a node shared by several
+	// versions of a trie can be hashed by concurrent readers.
+	memo atomic.Value
+	// clean is set when the node is known to be stored, i.e. it was loaded
+	// or committed, and it's reset when the node is updated.
+	clean bool
+}
+
+// memo holds the serialization and hash of a node with a hasher, the serialization
+// depends on the hasher too, since it contains the hashes of the children.
+type memo struct {
+	hasher     Hasher
+	serialized []byte
+	hash       []byte
+}
+
+// load returns the memo for the hasher, which is empty if there is none.
+func (c *cache) load(h Hasher) *memo {
+	if m, ok := c.memo.Load().(*memo); ok && SameHasher(m.hasher, h) {
+		return m
+	}
+	return &memo{hasher: h}
+}
+
+// set memoizes the serialization and hash of the node with the hasher, the hash may be nil.
+func (c *cache) set(h Hasher, serialized, hash []byte) {
+	c.memo.Store(&memo{hasher: h, serialized: serialized, hash: hash})
+}
+
+// reset forgets the memoized serialization and hash, and marks the node as dirty,
+// after the node was updated.
+func (c *cache) reset() {
+	c.memo.Store(&memo{})
+	c.clean = false
+}
+
+func (c *cache) serialize(n cached, h Hasher) ([]byte, error) {
+	m := c.load(h)
+	if m.serialized != nil {
+		return m.serialized, nil
+	}
+
+	serialized, err := encode(n.rawWith(h))
+	if err != nil {
+		return nil, err
+	}
+	c.set(h, serialized, nil)
+	return serialized, nil
+}
+
+// hashOf returns the memoized hash, or nil if the node can't be serialized.
+func (c *cache) hashOf(n cached, h Hasher) []byte {
+	m := c.load(h)
+	if m.hash != nil {
+		return m.hash
+	}
+
+	serialized, err := c.serialize(n, h)
+	if err != nil {
+		return nil
+	}
+	hash := h.Hash(serialized)
+	c.set(h, serialized, hash)
+	return hash
+}
+
+// cached is implemented by the nodes holding a cache
+type cached interface {
+	Node
+	nodeCache() *cache
+	// rawWith returns the raw form of the node, whose children are referenced
+	// by their hashes with the hasher.
+	rawWith(h Hasher) []interface{}
+}
+
+// IsHashed returns whether the hash of the node with the hasher is memoized, so that
+// it's not computed again. The empty node and a hash node don't need to be hashed.
+func IsHashed(h Hasher, n Node) bool {
+	if IsEmptyNode(n) {
+		return true
+	}
+	if _, ok := n.(HashNode); ok {
+		return true
+	}
+	c, ok := n.(cached)
+	if !ok {
+		return false
+	}
+	return c.nodeCache().load(h).hash != nil
+}
+
+// IsDirty returns whether the node was created or updated since it was loaded or committed.
+// A node that is not dirty only has descendants that are not dirty either.
+func IsDirty(n Node) bool {
+	c, ok := n.(cached)
+	if !ok {
+		return false
+	}
+	return !c.nodeCache().clean
+}
+
+// MarkClean marks the node as stored, after it was committed.
+func MarkClean(n Node) {
+	if c, ok := n.(cached); ok {
+		c.nodeCache().clean = true
+	}
+}
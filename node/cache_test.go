@@ -0,0 +1,62 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheReset(t *testing.T) {
+	leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+	b := NewBranchNode()
+	b.SetBranch(0, leaf)
+	hash := b.Hash()
+
+	b.SetValue([]byte("verb"))
+	require.NotEqual(t, hash, b.Hash())
+	b.RemoveValue()
+	require.Equal(t, hash, b.Hash())
+
+	e := NewExtensionNode([]nibble.Nibble{0, 1}, b)
+	hash = e.Hash()
+	e.SetNext(leaf)
+	require.NotEqual(t, hash, e.Hash())
+}
+
+func TestDirty(t *testing.T) {
+	leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+	require.True(t, IsDirty(leaf))
+	require.False(t, IsDirty(nil))
+	require.False(t, IsDirty(HashNode(leaf.Hash())))
+
+	b := NewBranchNode()
+	b.SetBranch(0, leaf)
+	MarkClean(b)
+	require.False(t, IsDirty(b))
+
+	// updating a node makes it dirty again
+	b.SetValue([]byte("verb"))
+	require.True(t, IsDirty(b))
+}
+
+func TestCopy(t *testing.T) {
+	leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+	b := NewBranchNode()
+	b.SetBranch(0, leaf)
+	hash := b.Hash()
+
+	copied := b.Copy()
+	require.Equal(t, hash, copied.Hash())
+	copied.SetValue([]byte("verb"))
+	require.NotEqual(t, hash, copied.Hash())
+	require.Equal(t, hash, b.Hash())
+	require.False(t, b.HasValue())
+
+	e := NewExtensionNode([]nibble.Nibble{0, 1}, b)
+	hash = e.Hash()
+	copiedExt := e.Copy()
+	copiedExt.SetNext(copied)
+	require.NotEqual(t, hash, copiedExt.Hash())
+	require.Equal(t, hash, e.Hash())
+}
@@ -0,0 +1,165 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+)
+
+var (
+	// ErrMissingNode is returned when a node referenced by its hash can't be found.
+	ErrMissingNode = errors.New("missing node")
+
+	// ErrCorruptNode is returned when a node can't be decoded from its serialization,
+	// or the serialization doesn't match the expected hash.
+	ErrCorruptNode = errors.New("corrupt node")
+)
+
+// Decode rebuilds a node from its RLP serialization, the inverse of Serialize.
+// If hash is not nil, it must be the hash of the serialization, and it's memoized
+// for the decoded node.
+// Children embedded in the serialization are decoded recursively, children
+// referenced by their hash are decoded as HashNode.
+func Decode(hash, buf []byte) (Node, error) {
+	return DecodeWith(Keccak256, hash, buf)
+}
+
+// DecodeWith rebuilds a node from its serialization with the hasher, the inverse of
+// SerializeWith, like Decode.
+func DecodeWith(h Hasher, hash, buf []byte) (Node, error) {
+	if hash != nil && !bytes.Equal(h.Hash(buf), hash) {
+		return nil, fmt.Errorf("%w: hash mismatch for node %x", ErrCorruptNode, hash)
+	}
+
+	n, err := decode(h, buf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptNode, err)
+	}
+
+	if c, ok := n.(cached); ok && hash != nil {
+		c.nodeCache().set(h, buf, hash)
+	}
+	return n, nil
+}
+
+func decode(h Hasher, buf []byte) (Node, error) {
+	kind, content, rest, err := rlp.Split(buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing bytes after node: %x", rest)
+	}
+
+	if kind != rlp.List {
+		// the empty node is serialized as an empty string
+		if len(content) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unexpected string of %v bytes", len(content))
+	}
+
+	count, err := rlp.CountValues(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var n Node
+	switch count {
+	case 2:
+		n, err = decodeShort(h, content)
+	case 17:
+		n, err = decodeBranch(h, content)
+	default:
+		return nil, fmt.Errorf("invalid number of list elements: %v", count)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the node is decoded from where it's stored, so it's not dirty
+	MarkClean(n)
+	return n, nil
+}
+
+// decodeShort decodes a leaf node or an extension node,
+// the prefix of the path tells which one it is.
+func decodeShort(h Hasher, elems []byte) (Node, error) {
+	prefixed, rest, err := rlp.SplitString(elems)
+	if err != nil {
+		return nil, err
+	}
+
+	path, isLeaf, err := nibble.PathFromPrefixedBytes(prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	if isLeaf {
+		value, _, err := rlp.SplitString(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid leaf value: %w", err)
+		}
+		return NewLeafNodeFromPath(path, value), nil
+	}
+
+	next, _, err := decodeChild(h, rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extension next node: %w", err)
+	}
+	if IsEmptyNode(next) {
+		return nil, fmt.Errorf("extension node without next node")
+	}
+	return NewExtensionNodeFromPath(path, next), nil
+}
+
+func decodeBranch(h Hasher, elems []byte) (Node, error) {
+	branch := NewBranchNode()
+	for i := 0; i < 16; i++ {
+		child, rest, err := decodeChild(h, elems)
+		if err != nil {
+			return nil, fmt.Errorf("invalid branch %v: %w", i, err)
+		}
+		branch.Branches[i] = child
+		elems = rest
+	}
+
+	value, _, err := rlp.SplitString(elems)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch value: %w", err)
+	}
+	if len(value) > 0 {
+		branch.SetValue(value)
+	}
+	return branch, nil
+}
+
+// decodeChild decodes the first child reference in buf, and returns the remaining bytes.
+// A child is either embedded as a list, referenced by its hash or empty.
+func decodeChild(h Hasher, buf []byte) (Node, []byte, error) {
+	kind, content, rest, err := rlp.Split(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if kind == rlp.List {
+		size := len(buf) - len(rest)
+		if size >= h.Size() {
+			return nil, nil, fmt.Errorf("embedded node of %v bytes, should be referenced by hash", size)
+		}
+		n, err := decode(h, buf[:size])
+		return n, rest, err
+	}
+
+	switch len(content) {
+	case 0:
+		return nil, rest, nil
+	case h.Size():
+		return HashNode(content), rest, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid hash reference of %v bytes", len(content))
+	}
+}
@@ -0,0 +1,103 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLeaf(t *testing.T) {
+	for _, path := range [][]nibble.Nibble{{}, {5}, {5, 0}, {5, 0, 6}} {
+		leaf := NewLeafNodeFromNibbles(path, []byte("coin"))
+		n, err := Decode(leaf.Hash(), serialize(t, leaf))
+		require.NoError(t, err)
+		decoded, ok := n.(*LeafNode)
+		require.True(t, ok)
+		require.Equal(t, path, decoded.Path.AppendTo([]nibble.Nibble{}))
+		require.Equal(t, leaf.Value, decoded.Value)
+		require.False(t, IsDirty(decoded))
+	}
+}
+
+func TestDecodeExtension(t *testing.T) {
+	leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+	b := NewBranchNode()
+	b.SetBranch(0, leaf)
+	b.SetValue([]byte("verb"))
+
+	ns := []nibble.Nibble{0, 1, 0, 2, 0, 3, 0, 4}
+	e := NewExtensionNode(ns, b)
+
+	n, err := Decode(e.Hash(), serialize(t, e))
+	require.NoError(t, err)
+	ext, ok := n.(*ExtensionNode)
+	require.True(t, ok)
+	require.Equal(t, ns, ext.Path.Nibbles())
+	// the branch node is serialized to less than 32 bytes, so it's embedded
+	branch, ok := ext.Next.(*BranchNode)
+	require.True(t, ok)
+	require.Equal(t, serialize(t, b), serialize(t, branch))
+	require.False(t, IsDirty(branch))
+	require.Equal(t, serialize(t, e), serialize(t, ext))
+}
+
+func TestDecodeBranch(t *testing.T) {
+	inline := NewLeafNodeFromNibbles([]nibble.Nibble{5}, []byte("coin"))
+	hashed := NewLeafNodeFromNibbles([]nibble.Nibble{5}, bytes.Repeat([]byte("coin"), 10))
+
+	b := NewBranchNode()
+	b.SetBranch(1, inline)
+	b.SetBranch(15, hashed)
+	b.SetValue([]byte("verb"))
+
+	n, err := Decode(b.Hash(), serialize(t, b))
+	require.NoError(t, err)
+	branch, ok := n.(*BranchNode)
+	require.True(t, ok)
+	require.Equal(t, serialize(t, inline), serialize(t, branch.Branches[1]))
+	require.Equal(t, HashNode(hashed.Hash()), branch.Branches[15])
+	require.Equal(t, []byte("verb"), branch.Value)
+	require.Equal(t, b.Hash(), branch.Hash())
+
+	for i, child := range branch.Branches {
+		if i != 1 && i != 15 {
+			require.True(t, IsEmptyNode(child))
+		}
+	}
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	n, err := Decode(EmptyNodeHash, serialize(t, nil))
+	require.NoError(t, err)
+	require.True(t, IsEmptyNode(n))
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+	serialized := serialize(t, leaf)
+
+	t.Run("should fail if the hash doesn't match", func(t *testing.T) {
+		tampered := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coil"))
+		_, err := Decode(leaf.Hash(), serialize(t, tampered))
+		require.True(t, errors.Is(err, ErrCorruptNode), err)
+	})
+
+	cases := map[string][]byte{
+		"truncated":           serialized[:len(serialized)-1],
+		"trailing bytes":      append(append([]byte{}, serialized...), 0x80),
+		"invalid path prefix": {0xc3, 0x40, 0x80, 0x80},
+		"invalid padding":     {0xc4, 0x82, 0x01, 0x02, 0x80},
+		"wrong list length":   {0xc3, 0x80, 0x80, 0x80},
+		"non-empty string":    {0x83, 0x01, 0x02, 0x03},
+		"short hash":          {0xc5, 0x82, 0x00, 0x01, 0x81, 0x99},
+	}
+	for name, buf := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode(nil, buf)
+			require.True(t, errors.Is(err, ErrCorruptNode), err)
+		})
+	}
+}
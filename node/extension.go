@@ -1,37 +1,59 @@
 package node
 
 import (
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mpetrun5/merkle-patricia-trie/nibble"
 )
 
 type ExtensionNode struct {
-	Path []nibble.Nibble
-	Next Node
+	Path  nibble.Path
+	Next  Node
+	cache cache
 }
 
 func NewExtensionNode(nibbles []nibble.Nibble, next Node) *ExtensionNode {
+	return NewExtensionNodeFromPath(nibble.NewPath(nibbles), next)
+}
+
+func NewExtensionNodeFromPath(path nibble.Path, next Node) *ExtensionNode {
 	return &ExtensionNode{
-		Path: nibbles,
+		Path: path,
 		Next: next,
 	}
 }
 
-func (e ExtensionNode) Hash() []byte {
-	return crypto.Keccak256(e.Serialize())
+// Copy returns a copy of the extension node sharing its next node, which can be updated
+// without affecting the original node.
+func (e *ExtensionNode) Copy() *ExtensionNode {
+	return &ExtensionNode{
+		Path: e.Path,
+		Next: e.Next,
+	}
 }
 
-func (e ExtensionNode) Raw() []interface{} {
+func (e *ExtensionNode) Hash() []byte {
+	return e.cache.hashOf(e, Keccak256)
+}
+
+func (e *ExtensionNode) SetNext(next Node) {
+	e.Next = next
+	e.cache.reset()
+}
+
+func (e *ExtensionNode) Raw() []interface{} {
+	return e.rawWith(Keccak256)
+}
+
+func (e *ExtensionNode) rawWith(h Hasher) []interface{} {
 	hashes := make([]interface{}, 2)
-	hashes[0] = nibble.ToBytes(nibble.ToPrefixed(e.Path, false))
-	if len(Serialize(e.Next)) >= 32 {
-		hashes[1] = e.Next.Hash()
-	} else {
-		hashes[1] = e.Next.Raw()
-	}
+	hashes[0] = e.Path.ToPrefixedBytes(false)
+	hashes[1] = rawChild(h, e.Next)
 	return hashes
 }
 
-func (e ExtensionNode) Serialize() []byte {
-	return Serialize(e)
+func (e *ExtensionNode) Serialize() ([]byte, error) {
+	return e.cache.serialize(e, Keccak256)
+}
+
+func (e *ExtensionNode) nodeCache() *cache {
+	return &e.cache
 }
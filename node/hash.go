@@ -0,0 +1,15 @@
+package node
+
+// HashNode is a reference to a node which is not loaded, only its hash is known.
+// It's what a child node, whose serialization is at least 32 bytes, is decoded to.
+type HashNode []byte
+
+func (h HashNode) Hash() []byte {
+	return h
+}
+
+// Raw returns nil, since the content of the referenced node is unknown.
+// A hash node is always embedded in its parent by its hash.
+func (h HashNode) Raw() []interface{} {
+	return nil
+}
@@ -0,0 +1,87 @@
+package node
+
+import (
+	"crypto/sha256"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Hasher hashes the serialized nodes of a trie. The nodes serialized to fewer bytes
+// than the size of the hashes are embedded in their parent, instead of being referenced
+// by their hash, so that both can be told apart when decoding the parent.
+// The hashes memoized by the nodes are only reused for the same hasher, i.e. an equal
+// hasher of the same type. A hasher whose type isn't comparable, such as a struct holding
+// a func, is never the same as another one, so a trie created WithHasher gives it an
+// identity, otherwise its hashes would be computed again each time they're needed.
+type Hasher interface {
+	// Hash returns the hash of the serialized node.
+	Hash(data []byte) []byte
+	// Size returns the length of the hashes in bytes.
+	Size() int
+}
+
+var (
+	// Keccak256 is the hasher of Ethereum's tries, it's the hasher of the functions
+	// and methods which don't take one.
+	Keccak256 Hasher = keccak256Hasher{}
+
+	// SHA256 hashes the nodes with SHA-256.
+	SHA256 Hasher = sha256Hasher{}
+
+	// BLAKE2b256 hashes the nodes with BLAKE2b, with 32 bytes hashes.
+	BLAKE2b256 Hasher = blake2b256Hasher{}
+)
+
+type keccak256Hasher struct{}
+
+func (keccak256Hasher) Hash(data []byte) []byte {
+	return crypto.Keccak256(data)
+}
+
+func (keccak256Hasher) Size() int {
+	return 32
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Hash(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func (sha256Hasher) Size() int {
+	return sha256.Size
+}
+
+type blake2b256Hasher struct{}
+
+func (blake2b256Hasher) Hash(data []byte) []byte {
+	hash := blake2b.Sum256(data)
+	return hash[:]
+}
+
+func (blake2b256Hasher) Size() int {
+	return blake2b.Size256
+}
+
+// EmptyRoot returns the root hash of an empty trie hashed with h,
+// which is EmptyNodeHash for Keccak256.
+func EmptyRoot(h Hasher) []byte {
+	if SameHasher(h, Keccak256) {
+		return EmptyNodeHash
+	}
+	serialized, _ := encode(EmptyNodeRaw)
+	return h.Hash(serialized)
+}
+
+// SameHasher returns whether both hashers are the same, which is never the case if their
+// type isn't comparable, instead of panicking like comparing them with ==.
+func SameHasher(a, b Hasher) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || t == nil || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
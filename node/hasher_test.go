@@ -0,0 +1,109 @@
+package node
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/stretchr/testify/require"
+)
+
+// sha160Hasher truncates SHA-256 hashes to 20 bytes, to test hashers whose hashes
+// are shorter than 32 bytes.
+type sha160Hasher struct{}
+
+func (sha160Hasher) Hash(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:20]
+}
+
+func (sha160Hasher) Size() int {
+	return 20
+}
+
+// funcHasher hashes with a func, its type isn't comparable.
+type funcHasher struct {
+	hash func(data []byte) []byte
+}
+
+func (h funcHasher) Hash(data []byte) []byte {
+	return h.hash(data)
+}
+
+func (funcHasher) Size() int {
+	return 32
+}
+
+func TestHasher(t *testing.T) {
+	t.Run("should hash the empty trie", func(t *testing.T) {
+		require.Equal(t, EmptyNodeHash, EmptyRoot(Keccak256))
+		emptySHA256 := sha256.Sum256([]byte{0x80})
+		require.Equal(t, emptySHA256[:], EmptyRoot(SHA256))
+		require.Equal(t, emptySHA256[:], HashWith(SHA256, nil))
+		require.Len(t, EmptyRoot(BLAKE2b256), 32)
+	})
+
+	t.Run("should decode the nodes serialized with the hasher", func(t *testing.T) {
+		for _, h := range []Hasher{Keccak256, SHA256, BLAKE2b256, sha160Hasher{}} {
+			b := NewBranchNode()
+			b.SetBranch(0, NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin")))
+			b.SetBranch(1, NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("a value long enough to be hashed")))
+
+			serialized, err := SerializeWith(h, b)
+			require.NoError(t, err)
+			hash := HashWith(h, b)
+			require.Equal(t, h.Hash(serialized), hash)
+
+			decoded, err := DecodeWith(h, hash, serialized)
+			require.NoError(t, err)
+			branch := decoded.(*BranchNode)
+			require.IsType(t, &LeafNode{}, branch.Branches[0])
+			require.Equal(t, HashNode(HashWith(h, b.Branches[1])), branch.Branches[1])
+			require.Equal(t, hash, HashWith(h, decoded))
+		}
+	})
+
+	t.Run("should embed the nodes serialized to less than the size of a hash", func(t *testing.T) {
+		// a leaf serialized to 26 bytes is hashed with 20 bytes hashes, but not with 32 bytes ones
+		leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("twenty one bytes long"))
+		serialized, err := Serialize(leaf)
+		require.NoError(t, err)
+		require.True(t, len(serialized) >= 20 && len(serialized) < 32, len(serialized))
+
+		b := NewBranchNode()
+		b.SetBranch(0, leaf)
+		require.Equal(t, rlp.RawValue(serialized), b.Raw()[0])
+		require.Equal(t, sha160Hasher{}.Hash(serialized), b.rawWith(sha160Hasher{})[0])
+	})
+
+	t.Run("should not reuse the hash memoized with another hasher", func(t *testing.T) {
+		leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+		keccak := HashWith(Keccak256, leaf)
+		sha := HashWith(SHA256, leaf)
+		require.NotEqual(t, keccak, sha)
+		require.Equal(t, keccak, leaf.Hash())
+		require.Equal(t, sha, HashWith(SHA256, leaf))
+	})
+
+	t.Run("should hash with a hasher which isn't comparable", func(t *testing.T) {
+		h := funcHasher{hash: SHA256.Hash}
+		require.True(t, SameHasher(SHA256, SHA256))
+		require.False(t, SameHasher(SHA256, Keccak256))
+		require.False(t, SameHasher(h, h))
+
+		b := NewBranchNode()
+		b.SetBranch(0, NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("a value long enough to be hashed")))
+		require.Equal(t, HashWith(SHA256, b), HashWith(h, b))
+		require.Equal(t, EmptyRoot(SHA256), EmptyRoot(h))
+	})
+
+	t.Run("should reject a node hashed with another hasher", func(t *testing.T) {
+		leaf := NewLeafNodeFromNibbles([]nibble.Nibble{5, 0, 6}, []byte("coin"))
+		serialized, err := Serialize(leaf)
+		require.NoError(t, err)
+		_, err = DecodeWith(SHA256, leaf.Hash(), serialized)
+		require.True(t, errors.Is(err, ErrCorruptNode), err)
+	})
+}
@@ -3,13 +3,13 @@ package node
 import (
 	"fmt"
 
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mpetrun5/merkle-patricia-trie/nibble"
 )
 
 type LeafNode struct {
-	Path  []nibble.Nibble
+	Path  nibble.Path
 	Value []byte
+	cache cache
 }
 
 func NewLeafNodeFromNibbleBytes(nibbles []byte, value []byte) (*LeafNode, error) {
@@ -22,8 +22,12 @@ func NewLeafNodeFromNibbleBytes(nibbles []byte, value []byte) (*LeafNode, error)
 }
 
 func NewLeafNodeFromNibbles(nibbles []nibble.Nibble, value []byte) *LeafNode {
+	return NewLeafNodeFromPath(nibble.NewPath(nibbles), value)
+}
+
+func NewLeafNodeFromPath(path nibble.Path, value []byte) *LeafNode {
 	return &LeafNode{
-		Path:  nibbles,
+		Path:  path,
 		Value: value,
 	}
 }
@@ -33,19 +37,27 @@ func NewLeafNodeFromKeyValue(key, value string) *LeafNode {
 }
 
 func NewLeafNodeFromBytes(key, value []byte) *LeafNode {
-	return NewLeafNodeFromNibbles(nibble.FromBytes(key), value)
+	return NewLeafNodeFromPath(nibble.PathFromBytes(key), value)
+}
+
+func (l *LeafNode) Hash() []byte {
+	return l.cache.hashOf(l, Keccak256)
 }
 
-func (l LeafNode) Hash() []byte {
-	return crypto.Keccak256(l.Serialize())
+func (l *LeafNode) Raw() []interface{} {
+	return l.rawWith(Keccak256)
 }
 
-func (l LeafNode) Raw() []interface{} {
-	path := nibble.ToBytes(nibble.ToPrefixed(l.Path, true))
+func (l *LeafNode) rawWith(h Hasher) []interface{} {
+	path := l.Path.ToPrefixedBytes(true)
 	raw := []interface{}{path, l.Value}
 	return raw
 }
 
-func (l LeafNode) Serialize() []byte {
-	return Serialize(l)
+func (l *LeafNode) Serialize() ([]byte, error) {
+	return l.cache.serialize(l, Keccak256)
+}
+
+func (l *LeafNode) nodeCache() *cache {
+	return &l.cache
 }
@@ -15,8 +15,8 @@ func printEachCalculationSteps(key, value []byte, isLeaf bool) map[string]string
 	hexs["key in nibbles"] = fmt.Sprintf("%x", nibble.FromBytes(key))
 	hexs["key in nibbles, and prefixed"] = fmt.Sprintf("%x", nibble.ToPrefixed(nibble.FromBytes(key), isLeaf))
 	hexs["key in nibbles, and prefixed, and convert back to buffer"] =
-		fmt.Sprintf("%x", nibble.ToBytes(nibble.ToPrefixed(nibble.FromBytes(key), isLeaf)))
-	beforeRLP := [][]byte{nibble.ToBytes(nibble.ToPrefixed(nibble.FromBytes(key), isLeaf)), value}
+		fmt.Sprintf("%x", nibble.ToPrefixedBytes(nibble.FromBytes(key), isLeaf))
+	beforeRLP := [][]byte{nibble.ToPrefixedBytes(nibble.FromBytes(key), isLeaf), value}
 	hexs["beforeRLP"] = fmt.Sprintf("%x", beforeRLP)
 	afterRLP, err := rlp.EncodeToBytes(beforeRLP)
 	if err != nil {
@@ -38,7 +38,7 @@ func TestLeafHash(t *testing.T) {
 	require.Equal(t, "02000001000200030004", fmt.Sprintf("%x", nibble.ToPrefixed(nibble.FromBytes([]byte{1, 2, 3, 4}), true)))
 
 	// ToBuffer
-	require.Equal(t, "2001020304", fmt.Sprintf("%x", nibble.ToBytes(nibble.ToPrefixed(nibble.FromBytes([]byte{1, 2, 3, 4}), true))))
+	require.Equal(t, "2001020304", fmt.Sprintf("%x", nibble.ToPrefixedBytes(nibble.FromBytes([]byte{1, 2, 3, 4}), true)))
 
 	require.Equal(t, "636f696e", fmt.Sprintf("%x", []byte("coin")))
 }
@@ -2,6 +2,7 @@ package node
 
 import (
 	"encoding/hex"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -12,32 +13,86 @@ var (
 )
 
 type Node interface {
+	// Hash returns the keccak256 hash of the serialized node, which is nil if the node
+	// can't be serialized, see Serialize for the error.
 	Hash() []byte // common.Hash
 	Raw() []interface{}
 }
 
+// Hash returns the hash of the node, which is nil if the node can't be serialized.
 func Hash(node Node) []byte {
+	return HashWith(Keccak256, node)
+}
+
+// HashWith returns the hash of the node with the hasher, which is nil if the node
+// can't be serialized.
+func HashWith(h Hasher, node Node) []byte {
 	if IsEmptyNode(node) {
-		return EmptyNodeHash
+		return EmptyRoot(h)
 	}
+
+	if c, ok := node.(cached); ok {
+		return c.nodeCache().hashOf(c, h)
+	}
+
 	return node.Hash()
 }
 
-func Serialize(node Node) []byte {
-	var raw interface{}
+// Serialize returns the RLP serialization of the node. An error wrapping ErrCorruptNode
+// is returned if the node can't be encoded.
+func Serialize(node Node) ([]byte, error) {
+	return SerializeWith(Keccak256, node)
+}
 
+// SerializeWith returns the RLP serialization of the node, whose children are
+// referenced by their hashes with the hasher.
+func SerializeWith(h Hasher, node Node) ([]byte, error) {
 	if IsEmptyNode(node) {
-		raw = EmptyNodeRaw
-	} else {
-		raw = node.Raw()
+		return encode(EmptyNodeRaw)
+	}
+
+	if c, ok := node.(cached); ok {
+		return c.nodeCache().serialize(c, h)
 	}
 
+	return encode(node.Raw())
+}
+
+func encode(raw interface{}) ([]byte, error) {
 	rlp, err := rlp.EncodeToBytes(raw)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%w: could not encode node: %v", ErrCorruptNode, err)
+	}
+
+	return rlp, nil
+}
+
+// rawChild returns the form in which a child node is embedded in its parent.
+func rawChild(h Hasher, child Node) interface{} {
+	if IsEmptyNode(child) {
+		return EmptyNodeRaw
+	}
+
+	// a hash node is only a reference, it can't be embedded by value
+	if hash, ok := child.(HashNode); ok {
+		return []byte(hash)
+	}
+
+	serialized, err := SerializeWith(h, child)
+	if err != nil {
+		// embed the child as is, so that encoding the parent fails with the same error
+		return child.Raw()
+	}
+	if len(serialized) >= h.Size() {
+		return HashWith(h, child)
 	}
 
-	return rlp
+	// if node can be serialized to less than the size of a hash, then
+	// use Serialized directly.
+	// it has to be ">=", rather than ">",
+	// so that when deserialized, the content can be distinguished
+	// by length
+	return rlp.RawValue(serialized)
 }
 
 func IsEmptyNode(node Node) bool {
@@ -0,0 +1,48 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/stretchr/testify/require"
+)
+
+func serialize(t *testing.T, n Node) []byte {
+	serialized, err := Serialize(n)
+	require.NoError(t, err)
+	return serialized
+}
+
+// invalidNode is a node whose raw form can't be RLP encoded
+type invalidNode struct{}
+
+func (invalidNode) Hash() []byte {
+	return nil
+}
+
+func (invalidNode) Raw() []interface{} {
+	return []interface{}{func() {}}
+}
+
+func TestSerialize(t *testing.T) {
+	t.Run("should serialize the empty node", func(t *testing.T) {
+		require.Equal(t, []byte{0x80}, serialize(t, nil))
+		require.Equal(t, EmptyNodeHash, Hash(nil))
+	})
+
+	t.Run("should fail to serialize a node that can't be encoded", func(t *testing.T) {
+		_, err := Serialize(invalidNode{})
+		require.True(t, errors.Is(err, ErrCorruptNode), err)
+	})
+
+	t.Run("should fail to serialize a node with a child that can't be encoded", func(t *testing.T) {
+		b := NewBranchNode()
+		b.SetBranch(0, invalidNode{})
+		e := NewExtensionNode([]nibble.Nibble{1}, b)
+
+		_, err := Serialize(e)
+		require.True(t, errors.Is(err, ErrCorruptNode), err)
+		require.Nil(t, e.Hash())
+	})
+}
@@ -0,0 +1,192 @@
+package proof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ErrResultMismatch is returned when a field of an eth_getProof result doesn't match
+// the value proven by its proof.
+var ErrResultMismatch = errors.New("result doesn't match proof")
+
+// AccountResult is the result of the eth_getProof RPC method, as specified by EIP-1186.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the proof of a storage slot in an eth_getProof result.
+type StorageResult struct {
+	// Key is the storage slot, either as a quantity such as "0x7", or as a word which may
+	// be padded with leading zeros up to 32 bytes.
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// Account is an account in Ethereum's state trie, it's stored RLP encoded
+// under the keccak256 hash of the address.
+type Account struct {
+	Nonce       uint64
+	Balance     *big.Int
+	StorageHash []byte
+	CodeHash    []byte
+}
+
+// ParseAccountResult parses the JSON result of an eth_getProof call.
+func ParseAccountResult(data []byte) (*AccountResult, error) {
+	var result AccountResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("could not parse eth_getProof result: %w", err)
+	}
+	return &result, nil
+}
+
+// VerifyAccountResult verifies the account proof of an eth_getProof result against the state root,
+// and each of its storage proofs against the storage hash of the account.
+// It returns the account decoded from the state trie, or nil if the proof shows the account
+// doesn't exist, in which case all the storage slots have to be zero.
+func VerifyAccountResult(stateRoot []byte, result *AccountResult) (*Account, error) {
+	account, err := VerifyAccountProof(stateRoot, result)
+	if err != nil {
+		return nil, err
+	}
+
+	storageHash := result.StorageHash[:]
+	for _, storage := range result.StorageProof {
+		if account == nil {
+			if toInt(storage.Value).Sign() != 0 {
+				return nil, fmt.Errorf("%w: storage %v of non-existing account is not zero", ErrResultMismatch, storage.Key)
+			}
+			continue
+		}
+
+		if err := VerifyStorageProof(storageHash, storage); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+// VerifyAccountProof verifies the account proof of an eth_getProof result against
+// the state root, and that the account fields of the result match the proven account.
+// It returns nil if the proof shows the account doesn't exist.
+func VerifyAccountProof(stateRoot []byte, result *AccountResult) (*Account, error) {
+	value, found, err := VerifyProof(stateRoot, crypto.Keccak256(result.Address[:]), proofOf(result.AccountProof))
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof for %v: %w", result.Address.Hex(), err)
+	}
+
+	if !found {
+		if result.Nonce != 0 || toInt(result.Balance).Sign() != 0 {
+			return nil, fmt.Errorf("%w: non-existing account %v has nonce or balance", ErrResultMismatch, result.Address.Hex())
+		}
+		return nil, nil
+	}
+
+	var account Account
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		return nil, fmt.Errorf("could not decode account %v: %w", result.Address.Hex(), err)
+	}
+
+	if account.Nonce != uint64(result.Nonce) {
+		return nil, fmt.Errorf("%w: nonce %v, proven %v", ErrResultMismatch, uint64(result.Nonce), account.Nonce)
+	}
+	if account.Balance.Cmp(toInt(result.Balance)) != 0 {
+		return nil, fmt.Errorf("%w: balance %v, proven %v", ErrResultMismatch, toInt(result.Balance), account.Balance)
+	}
+	if !bytes.Equal(account.StorageHash, result.StorageHash[:]) {
+		return nil, fmt.Errorf("%w: storage hash %v, proven %x", ErrResultMismatch, result.StorageHash.Hex(), account.StorageHash)
+	}
+	if !bytes.Equal(account.CodeHash, result.CodeHash[:]) {
+		return nil, fmt.Errorf("%w: code hash %v, proven %x", ErrResultMismatch, result.CodeHash.Hex(), account.CodeHash)
+	}
+	return &account, nil
+}
+
+// VerifyStorageProof verifies the storage proof against the storage hash of the account,
+// and that the value of the result matches the proven value.
+func VerifyStorageProof(storageHash []byte, storage StorageResult) error {
+	slot, err := storageSlot(storage.Key)
+	if err != nil {
+		return fmt.Errorf("invalid storage key %v: %w", storage.Key, err)
+	}
+
+	key := crypto.Keccak256(slot)
+	value, found, err := VerifyProof(storageHash, key, proofOf(storage.Proof))
+	if err != nil {
+		return fmt.Errorf("invalid storage proof for %v: %w", storage.Key, err)
+	}
+
+	// slots are stored as RLP encoded byte arrays, without leading zeros,
+	// and a zero slot is not stored at all.
+	proven := new(big.Int)
+	if found {
+		_, content, _, err := rlp.Split(value)
+		if err != nil {
+			return fmt.Errorf("could not decode storage %v: %w", storage.Key, err)
+		}
+		proven.SetBytes(content)
+	}
+
+	if proven.Cmp(toInt(storage.Value)) != 0 {
+		return fmt.Errorf("%w: storage %v is %v, proven %v", ErrResultMismatch, storage.Key, toInt(storage.Value), proven)
+	}
+	return nil
+}
+
+// storageSlot decodes the storage key of a result into the 32 bytes slot, the key is
+// left padded with zeros like common.HexToHash, but it can't be longer than 32 bytes.
+func storageSlot(key string) ([]byte, error) {
+	if !strings.HasPrefix(key, "0x") && !strings.HasPrefix(key, "0X") {
+		return nil, errors.New("missing 0x prefix")
+	}
+	digits := key[2:]
+	if len(digits) == 0 {
+		return nil, errors.New("empty key")
+	}
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+
+	slot, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, err
+	}
+	if len(slot) > common.HashLength {
+		return nil, fmt.Errorf("%v bytes, longer than %v bytes", len(slot), common.HashLength)
+	}
+	return common.LeftPadBytes(slot, common.HashLength), nil
+}
+
+// toInt converts the quantity, which is zero if it's missing.
+func toInt(quantity *hexutil.Big) *big.Int {
+	if quantity == nil {
+		return new(big.Int)
+	}
+	return quantity.ToInt()
+}
+
+// proofOf creates a proof from the list of nodes, keyed by their hashes.
+func proofOf(nodes []hexutil.Bytes) *ProofDB {
+	proof := NewProofDB()
+	for _, serialized := range nodes {
+		proof.Put(crypto.Keccak256(serialized), serialized)
+	}
+	return proof
+}
@@ -0,0 +1,125 @@
+package proof
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/stretchr/testify/require"
+)
+
+// eip1186Fixture holds eth_getProof results recorded from a state with a contract
+// account with storage, an externally owned account, and an account that doesn't exist.
+type eip1186Fixture struct {
+	StateRoot common.Hash     `json:"stateRoot"`
+	Contract  json.RawMessage `json:"contract"`
+	EOA       json.RawMessage `json:"eoa"`
+	Missing   json.RawMessage `json:"missing"`
+}
+
+func loadFixture(t *testing.T) (*eip1186Fixture, map[string]*AccountResult) {
+	data, err := os.ReadFile("testdata/eip1186.json")
+	require.NoError(t, err)
+
+	var fixture eip1186Fixture
+	require.NoError(t, json.Unmarshal(data, &fixture))
+
+	results := make(map[string]*AccountResult)
+	for name, raw := range map[string]json.RawMessage{
+		"contract": fixture.Contract,
+		"eoa":      fixture.EOA,
+		"missing":  fixture.Missing,
+	} {
+		result, err := ParseAccountResult(raw)
+		require.NoError(t, err)
+		results[name] = result
+	}
+	return &fixture, results
+}
+
+func TestVerifyAccountResult(t *testing.T) {
+	fixture, results := loadFixture(t)
+	stateRoot := fixture.StateRoot[:]
+
+	t.Run("should verify a contract account and its storage", func(t *testing.T) {
+		account, err := VerifyAccountResult(stateRoot, results["contract"])
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), account.Nonce)
+		require.Equal(t, big.NewInt(12345), account.Balance)
+		require.NotEqual(t, node.EmptyNodeHash, account.StorageHash)
+		// the last storage key is padded to 32 bytes, like in the results of some nodes
+		require.Len(t, results["contract"].StorageProof, 5)
+		require.Len(t, results["contract"].StorageProof[4].Key, 66)
+	})
+
+	t.Run("should verify an account without storage", func(t *testing.T) {
+		account, err := VerifyAccountResult(stateRoot, results["eoa"])
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), account.Nonce)
+		require.Equal(t, node.EmptyNodeHash, account.StorageHash)
+	})
+
+	t.Run("should verify an account doesn't exist", func(t *testing.T) {
+		account, err := VerifyAccountResult(stateRoot, results["missing"])
+		require.NoError(t, err)
+		require.Nil(t, account)
+	})
+
+	t.Run("should fail if the balance doesn't match", func(t *testing.T) {
+		_, results := loadFixture(t)
+		result := results["eoa"]
+		result.Balance = (*hexutil.Big)(big.NewInt(1))
+		_, err := VerifyAccountResult(stateRoot, result)
+		require.True(t, errors.Is(err, ErrResultMismatch), err)
+	})
+
+	t.Run("should fail if a storage value doesn't match", func(t *testing.T) {
+		_, results := loadFixture(t)
+		result := results["contract"]
+		result.StorageProof[1].Value = (*hexutil.Big)(big.NewInt(1))
+		_, err := VerifyAccountResult(stateRoot, result)
+		require.True(t, errors.Is(err, ErrResultMismatch), err)
+	})
+
+	t.Run("should fail if a storage slot is claimed to be set but isn't", func(t *testing.T) {
+		_, results := loadFixture(t)
+		result := results["contract"]
+		result.StorageProof[3].Value = (*hexutil.Big)(big.NewInt(1))
+		_, err := VerifyAccountResult(stateRoot, result)
+		require.True(t, errors.Is(err, ErrResultMismatch), err)
+	})
+
+	t.Run("should fail if the account proof is for another state", func(t *testing.T) {
+		otherRoot := common.HexToHash("0x01")
+		_, err := VerifyAccountResult(otherRoot[:], results["contract"])
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+
+	t.Run("should decode storage keys with leading zeros", func(t *testing.T) {
+		_, results := loadFixture(t)
+		storage := results["contract"].StorageProof[0]
+		for _, key := range []string{"0x0", "0x00", "0x" + strings.Repeat("00", 32)} {
+			storage.Key = key
+			require.NoError(t, VerifyStorageProof(results["contract"].StorageHash[:], storage), key)
+		}
+
+		for _, key := range []string{"0x" + strings.Repeat("00", 33), "0x", "00", "0xzz"} {
+			storage.Key = key
+			require.Error(t, VerifyStorageProof(results["contract"].StorageHash[:], storage), key)
+		}
+	})
+
+	t.Run("should fail if the storage proof is incomplete", func(t *testing.T) {
+		_, results := loadFixture(t)
+		result := results["contract"]
+		result.StorageProof[0].Proof = result.StorageProof[0].Proof[1:]
+		_, err := VerifyAccountResult(stateRoot, result)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
@@ -0,0 +1,75 @@
+package proof
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// encodedProof is the canonical form of a proof for a key.
+type encodedProof struct {
+	Key []byte
+	// Nodes are ordered from the root node to the last node on the path of the key.
+	Nodes [][]byte
+}
+
+// recorder records the nodes read from the proof, in the order they are read.
+type recorder struct {
+	Proof
+	nodes [][]byte
+}
+
+func (r *recorder) Get(key []byte) ([]byte, error) {
+	value, err := r.Proof.Get(key)
+	if err == nil {
+		r.nodes = append(r.nodes, value)
+	}
+	return value, err
+}
+
+// Encode returns the canonical encoding of the proof for the key under the root hash,
+// which is the RLP list of the key and the nodes on the path of the key, ordered from
+// the root node to the leaf node. Nodes of the proof which are not on the path are left out,
+// so the encoding only depends on the trie and the key.
+// An error is returned if the proof is not valid.
+func Encode(rootHash []byte, key []byte, proof Proof) ([]byte, error) {
+	return EncodeWithHasher(node.Keccak256, rootHash, key, proof)
+}
+
+// EncodeWithHasher returns the canonical encoding of the proof like Encode,
+// for a trie whose nodes are hashed with the hasher.
+func EncodeWithHasher(h node.Hasher, rootHash []byte, key []byte, proof Proof) ([]byte, error) {
+	r := &recorder{Proof: proof}
+	if _, _, err := newVerifier(h, rootHash, r).verify(key); err != nil {
+		return nil, fmt.Errorf("could not encode invalid proof: %w", err)
+	}
+
+	encoded, err := rlp.EncodeToBytes(encodedProof{Key: key, Nodes: r.nodes})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode proof: %w", err)
+	}
+	return encoded, nil
+}
+
+// Decode decodes a proof encoded by Encode, and returns the key and the proof for it.
+func Decode(encoded []byte) ([]byte, *ProofDB, error) {
+	return DecodeWithHasher(node.Keccak256, encoded)
+}
+
+// DecodeWithHasher decodes a proof encoded by EncodeWithHasher with the same hasher,
+// the nodes of the proof are keyed by their hashes with the hasher.
+func DecodeWithHasher(h node.Hasher, encoded []byte) ([]byte, *ProofDB, error) {
+	var decoded encodedProof
+	if err := rlp.DecodeBytes(encoded, &decoded); err != nil {
+		return nil, nil, fmt.Errorf("could not decode proof: %w", err)
+	}
+
+	proof := NewProofDB()
+	for _, serialized := range decoded.Nodes {
+		if err := proof.Put(h.Hash(serialized), serialized); err != nil {
+			return nil, nil, err
+		}
+	}
+	return decoded.Key, proof, nil
+}
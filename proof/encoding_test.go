@@ -0,0 +1,76 @@
+package proof
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	mpt := newEthTrie()
+	rootHash := mpt.Hash()
+	key := []byte{1, 2, 4}
+	proof := ethProof(t, mpt, key)
+
+	t.Run("should encode the nodes from the root node to the leaf node", func(t *testing.T) {
+		encoded, err := Encode(rootHash[:], key, proof)
+		require.NoError(t, err)
+		require.Equal(t, "f9012383010204f9011ca3e210a0c0952f1896f271d67267de1297570cfdfa389e8fbac3a299f8f9f8a2d98478d2b83cf83a80a0928213d5ff24c7843d2a55abaf62490cc0a8c56d3cff8ac439606b190399d5d7808080c98320060784747269658080808080808080808080a5e4821020a0cc1cb59b0c9eadc255669d82e7db1e537ed425b6b34153045ba7dbb41992ea06b853f851808080a0b8de2c414951bd7e2eb7061b4d76b5dcab38cd1cdd6d287ff02db3a7a748357fa0246f6c1a0bb3170f0ebdd945bba1ff37b85bee9b2c8dae357fc252e3baeb34b7808080808080808080808080b83df83b20b838612076616c75652074686174206973206c6f6e6720656e6f75676820746f206265207265666572656e636564206279206974732068617368", hex.EncodeToString(encoded))
+	})
+
+	t.Run("should not depend on the order the nodes were put", func(t *testing.T) {
+		nodes := proof.Serialize()
+		reversed := NewProofDB()
+		for i := len(nodes) - 1; i >= 0; i-- {
+			require.NoError(t, reversed.Put(crypto.Keccak256(nodes[i]), nodes[i]))
+		}
+		// and on nodes not on the path
+		empty, err := node.Serialize(nil)
+		require.NoError(t, err)
+		require.NoError(t, reversed.Put(node.EmptyNodeHash, empty))
+
+		expected, err := Encode(rootHash[:], key, proof)
+		require.NoError(t, err)
+		encoded, err := Encode(rootHash[:], key, reversed)
+		require.NoError(t, err)
+		require.Equal(t, expected, encoded)
+	})
+
+	t.Run("should fail to encode an invalid proof", func(t *testing.T) {
+		_, err := Encode(rootHash[:], key, NewProofDB())
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	mpt := newEthTrie()
+	rootHash := mpt.Hash()
+
+	for _, key := range [][]byte{{1, 2, 3}, {1, 2, 4}, {1, 2, 5}} {
+		encoded, err := Encode(rootHash[:], key, ethProof(t, mpt, key))
+		require.NoError(t, err)
+
+		decodedKey, proof, err := Decode(encoded)
+		require.NoError(t, err)
+		require.Equal(t, key, decodedKey)
+
+		expected, expectedFound, err := VerifyProof(rootHash[:], key, ethProof(t, mpt, key))
+		require.NoError(t, err)
+		value, found, err := VerifyProof(rootHash[:], decodedKey, proof)
+		require.NoError(t, err)
+		require.Equal(t, expectedFound, found)
+		require.Equal(t, expected, value)
+
+		// encoding the decoded proof gives the same bytes
+		reencoded, err := Encode(rootHash[:], decodedKey, proof)
+		require.NoError(t, err)
+		require.Equal(t, encoded, reencoded)
+	}
+
+	_, _, err := Decode([]byte{0x01, 0x02})
+	require.Error(t, err)
+}
@@ -21,8 +21,11 @@ type Proof interface {
 	Serialize() [][]byte
 }
 
+// ProofDB is a Proof keeping the nodes in memory, it serializes them
+// in the order they were first put.
 type ProofDB struct {
-	kv map[string][]byte
+	kv    map[string][]byte
+	order []string
 }
 
 func NewProofDB() *ProofDB {
@@ -33,13 +36,25 @@ func NewProofDB() *ProofDB {
 
 func (w *ProofDB) Put(key []byte, value []byte) error {
 	keyS := fmt.Sprintf("%x", key)
+	if _, ok := w.kv[keyS]; !ok {
+		w.order = append(w.order, keyS)
+	}
 	w.kv[keyS] = value
 	return nil
 }
 
 func (w *ProofDB) Delete(key []byte) error {
 	keyS := fmt.Sprintf("%x", key)
+	if _, ok := w.kv[keyS]; !ok {
+		return nil
+	}
 	delete(w.kv, keyS)
+	for i, k := range w.order {
+		if k == keyS {
+			w.order = append(w.order[:i], w.order[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 func (w *ProofDB) Has(key []byte) (bool, error) {
@@ -59,8 +74,8 @@ func (w *ProofDB) Get(key []byte) ([]byte, error) {
 
 func (w *ProofDB) Serialize() [][]byte {
 	nodes := make([][]byte, 0, len(w.kv))
-	for _, value := range w.kv {
-		nodes = append(nodes, value)
+	for _, keyS := range w.order {
+		nodes = append(nodes, w.kv[keyS])
 	}
 	return nodes
 }
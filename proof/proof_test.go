@@ -0,0 +1,21 @@
+package proof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofDBSerializeOrder(t *testing.T) {
+	proof := NewProofDB()
+	require.NoError(t, proof.Put([]byte{3}, []byte("c")))
+	require.NoError(t, proof.Put([]byte{1}, []byte("a")))
+	require.NoError(t, proof.Put([]byte{2}, []byte("b")))
+	// putting a key again doesn't change its position
+	require.NoError(t, proof.Put([]byte{3}, []byte("c")))
+	require.Equal(t, [][]byte{[]byte("c"), []byte("a"), []byte("b")}, proof.Serialize())
+
+	require.NoError(t, proof.Delete([]byte{1}))
+	require.NoError(t, proof.Delete([]byte{4}))
+	require.Equal(t, [][]byte{[]byte("c"), []byte("b")}, proof.Serialize())
+}
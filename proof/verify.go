@@ -0,0 +1,164 @@
+package proof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// ErrKeyExists is returned when verifying the absence of a key that is in the trie.
+var ErrKeyExists = errors.New("key exists")
+
+// VerifyProof verifies the proof for the given key under the given root hash,
+// by walking the proof nodes from the root node along the path of the key.
+// It returns the value and true if the proof shows the key is in the trie,
+// or nil and false if the proof shows the key is not in the trie.
+// An error wrapping node.ErrMissingNode or node.ErrCorruptNode is returned
+// if the proof doesn't contain a node on the path, or a node was tampered with.
+func VerifyProof(rootHash []byte, key []byte, proof Proof) (value []byte, found bool, err error) {
+	return VerifyProofWithHasher(node.Keccak256, rootHash, key, proof)
+}
+
+// VerifyProofWithHasher verifies the proof like VerifyProof, for a trie whose nodes
+// are hashed with the hasher.
+func VerifyProofWithHasher(h node.Hasher, rootHash []byte, key []byte, proof Proof) (value []byte, found bool, err error) {
+	return newVerifier(h, rootHash, proof).verify(key)
+}
+
+// VerifyMultiProof verifies the proof for many keys under the given root hash,
+// which is the union of the proofs for each of the keys. Each node of the proof
+// is only decoded once, even if it's on the paths of several keys.
+// It returns the values for the keys in the same order, the value is nil if
+// the proof shows the key is not in the trie.
+func VerifyMultiProof(rootHash []byte, keys [][]byte, proof Proof) ([][]byte, error) {
+	return VerifyMultiProofWithHasher(node.Keccak256, rootHash, keys, proof)
+}
+
+// VerifyMultiProofWithHasher verifies the proof for many keys like VerifyMultiProof,
+// for a trie whose nodes are hashed with the hasher.
+func VerifyMultiProofWithHasher(h node.Hasher, rootHash []byte, keys [][]byte, proof Proof) ([][]byte, error) {
+	v := newVerifier(h, rootHash, proof)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, found, err := v.verify(key)
+		if err != nil {
+			return nil, fmt.Errorf("could not verify key %x: %w", key, err)
+		}
+		if found {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
+
+// verifier walks the proof nodes from the root node, it keeps the decoded nodes
+// so that they are shared by the paths of different keys.
+type verifier struct {
+	hasher  node.Hasher
+	root    node.Node
+	proof   Proof
+	decoded map[string]node.Node
+}
+
+func newVerifier(h node.Hasher, rootHash []byte, proof Proof) *verifier {
+	v := &verifier{
+		hasher:  h,
+		proof:   proof,
+		decoded: make(map[string]node.Node),
+	}
+	if !bytes.Equal(rootHash, node.EmptyRoot(h)) {
+		v.root = node.HashNode(rootHash)
+	}
+	return v
+}
+
+func (v *verifier) verify(key []byte) (value []byte, found bool, err error) {
+	n := v.root
+	nibbles := nibble.FromBytes(key)
+	for {
+		if hash, ok := n.(node.HashNode); ok {
+			n, err = v.resolve(hash)
+			if err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		if node.IsEmptyNode(n) {
+			return nil, false, nil
+		}
+
+		if leaf, ok := n.(*node.LeafNode); ok {
+			matched := leaf.Path.PrefixMatchedLen(nibbles)
+			if matched != leaf.Path.Len() || matched != len(nibbles) {
+				return nil, false, nil
+			}
+			return leaf.Value, true, nil
+		}
+
+		if branch, ok := n.(*node.BranchNode); ok {
+			if len(nibbles) == 0 {
+				return branch.Value, branch.HasValue(), nil
+			}
+
+			b, remaining := nibbles[0], nibbles[1:]
+			nibbles = remaining
+			n = branch.Branches[b]
+			continue
+		}
+
+		if ext, ok := n.(*node.ExtensionNode); ok {
+			matched := ext.Path.PrefixMatchedLen(nibbles)
+			if matched < ext.Path.Len() {
+				return nil, false, nil
+			}
+
+			nibbles = nibbles[matched:]
+			n = ext.Next
+			continue
+		}
+
+		return nil, false, fmt.Errorf("unknown node type %T", n)
+	}
+}
+
+// resolve loads the node with the given hash from the proof.
+func (v *verifier) resolve(hash node.HashNode) (node.Node, error) {
+	if n, ok := v.decoded[string(hash)]; ok {
+		return n, nil
+	}
+
+	buf, err := v.proof.Get(hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %x", node.ErrMissingNode, []byte(hash))
+	}
+
+	n, err := node.DecodeWith(v.hasher, hash, buf)
+	if err != nil {
+		return nil, err
+	}
+	v.decoded[string(hash)] = n
+	return n, nil
+}
+
+// VerifyAbsenceProof verifies the proof shows the given key is not in the trie
+// under the given root hash. It returns ErrKeyExists if the key is in the trie.
+func VerifyAbsenceProof(rootHash []byte, key []byte, proof Proof) error {
+	return VerifyAbsenceProofWithHasher(node.Keccak256, rootHash, key, proof)
+}
+
+// VerifyAbsenceProofWithHasher verifies the proof shows the given key is not in the trie
+// like VerifyAbsenceProof, for a trie whose nodes are hashed with the hasher.
+func VerifyAbsenceProofWithHasher(h node.Hasher, rootHash []byte, key []byte, proof Proof) error {
+	_, found, err := VerifyProofWithHasher(h, rootHash, key, proof)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: %x", ErrKeyExists, key)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package proof
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/stretchr/testify/require"
+)
+
+// ethProof generates the proof for the key with go-ethereum's trie implementation
+func ethProof(t *testing.T, mpt *trie.Trie, key []byte) *ProofDB {
+	proof := NewProofDB()
+	require.NoError(t, mpt.Prove(key, 0, proof))
+	return proof
+}
+
+func newEthTrie() *trie.Trie {
+	mpt := new(trie.Trie)
+	mpt.Update([]byte{1, 2, 3}, []byte("hello"))
+	mpt.Update([]byte{1, 2, 3, 4, 5}, []byte("world"))
+	mpt.Update([]byte{1, 2, 4}, []byte("a value that is long enough to be referenced by its hash"))
+	mpt.Update([]byte{5, 6, 7}, []byte("trie"))
+	return mpt
+}
+
+func TestVerifyProof(t *testing.T) {
+	mpt := newEthTrie()
+	rootHash := mpt.Hash()
+
+	t.Run("should return the value of an existing key", func(t *testing.T) {
+		for key, expected := range map[string]string{
+			"\x01\x02\x03":         "hello",
+			"\x01\x02\x03\x04\x05": "world",
+			"\x01\x02\x04":         "a value that is long enough to be referenced by its hash",
+			"\x05\x06\x07":         "trie",
+		} {
+			value, found, err := VerifyProof(rootHash[:], []byte(key), ethProof(t, mpt, []byte(key)))
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, []byte(expected), value)
+		}
+	})
+
+	t.Run("should prove a key is absent", func(t *testing.T) {
+		for _, key := range [][]byte{{1, 2}, {1, 2, 3, 4}, {1, 2, 5}, {5, 6, 7, 8}, {9}} {
+			value, found, err := VerifyProof(rootHash[:], key, ethProof(t, mpt, key))
+			require.NoError(t, err)
+			require.False(t, found)
+			require.Nil(t, value)
+		}
+	})
+
+	t.Run("should prove any key is absent from an empty trie", func(t *testing.T) {
+		value, found, err := VerifyProof(node.EmptyNodeHash, []byte{1, 2, 3}, NewProofDB())
+		require.NoError(t, err)
+		require.False(t, found)
+		require.Nil(t, value)
+	})
+
+	t.Run("should fail if a node is missing", func(t *testing.T) {
+		key := []byte{1, 2, 4}
+		proof := ethProof(t, mpt, key)
+		require.NoError(t, proof.Delete(rootHash[:]))
+
+		_, _, err := VerifyProof(rootHash[:], key, proof)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+
+	t.Run("should fail if a node was tampered with", func(t *testing.T) {
+		key := []byte{1, 2, 4}
+		proof := ethProof(t, mpt, key)
+		for _, serialized := range proof.Serialize() {
+			n, err := node.Decode(nil, serialized)
+			require.NoError(t, err)
+			if leaf, ok := n.(*node.LeafNode); ok {
+				tampered := node.NewLeafNodeFromPath(leaf.Path, []byte("tampered"))
+				serialized, err := node.Serialize(tampered)
+				require.NoError(t, err)
+				require.NoError(t, proof.Put(leaf.Hash(), serialized))
+			}
+		}
+
+		_, _, err := VerifyProof(rootHash[:], key, proof)
+		require.True(t, errors.Is(err, node.ErrCorruptNode), err)
+	})
+
+	t.Run("should fail if the root hash doesn't match", func(t *testing.T) {
+		key := []byte{1, 2, 3}
+		proof := ethProof(t, mpt, key)
+		mpt := newEthTrie()
+		mpt.Update([]byte{9}, []byte("updated"))
+		updatedRootHash := mpt.Hash()
+
+		_, _, err := VerifyProof(updatedRootHash[:], key, proof)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
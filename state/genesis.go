@@ -0,0 +1,67 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+)
+
+// GenesisAlloc is the allocation of the accounts in the genesis state,
+// in the format of the alloc field of a genesis file.
+type GenesisAlloc map[common.Address]GenesisAccount
+
+// GenesisAccount is an account in the genesis state, the balance and the nonce
+// can be either hex or decimal.
+type GenesisAccount struct {
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+	Balance *math.HexOrDecimal256       `json:"balance"`
+	Nonce   math.HexOrDecimal64         `json:"nonce,omitempty"`
+}
+
+// ParseGenesisAlloc parses the JSON allocation of a genesis file.
+func ParseGenesisAlloc(data []byte) (GenesisAlloc, error) {
+	var alloc GenesisAlloc
+	if err := json.Unmarshal(data, &alloc); err != nil {
+		return nil, fmt.Errorf("could not parse genesis alloc: %w", err)
+	}
+	return alloc, nil
+}
+
+// NewGenesis creates the genesis state with the allocated accounts.
+// The key-value store can be nil for a state which is only kept in memory.
+func NewGenesis(alloc GenesisAlloc, db storage.KeyValueStore) (*StateTrie, error) {
+	s, err := New(nil, db)
+	if err != nil {
+		return nil, err
+	}
+
+	for addr, account := range alloc {
+		balance := new(big.Int)
+		if account.Balance != nil {
+			balance = (*big.Int)(account.Balance)
+		}
+		if err := s.SetBalance(addr, balance); err != nil {
+			return nil, err
+		}
+		if err := s.SetNonce(addr, uint64(account.Nonce)); err != nil {
+			return nil, err
+		}
+		if len(account.Code) > 0 {
+			if err := s.SetCode(addr, account.Code); err != nil {
+				return nil, err
+			}
+		}
+		for slot, value := range account.Storage {
+			if err := s.SetStorage(addr, slot, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"compress/gzip"
+	"io"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGenesis(t *testing.T) {
+	t.Run("should match the state root of the mainnet genesis block", func(t *testing.T) {
+		f, err := os.Open("testdata/mainnet_alloc.json.gz")
+		require.NoError(t, err)
+		defer f.Close()
+
+		r, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+
+		alloc, err := ParseGenesisAlloc(data)
+		require.NoError(t, err)
+		require.Len(t, alloc, 8893)
+
+		s, err := NewGenesis(alloc, nil)
+		require.NoError(t, err)
+
+		stateRoot := common.HexToHash("0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544")
+		require.Equal(t, stateRoot[:], stateHash(t, s))
+	})
+
+	t.Run("should allocate balances, nonces, code and storage", func(t *testing.T) {
+		alloc, err := ParseGenesisAlloc([]byte(`{
+			"0x0000000000000000000000000000000000000001": {"balance": "1000000000000000000"},
+			"0x0000000000000000000000000000000000000002": {
+				"balance": "0x0",
+				"nonce": "0x1",
+				"code": "0x6000",
+				"storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x000000000000000000000000000000000000000000000000000000000000002a"}
+			}
+		}`))
+		require.NoError(t, err)
+
+		s, err := NewGenesis(alloc, nil)
+		require.NoError(t, err)
+
+		account, err := s.GetAccount(alice)
+		require.NoError(t, err)
+		require.Equal(t, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil), account.Balance)
+
+		account, err = s.GetAccount(contract)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), account.Nonce)
+		require.NotEqual(t, EmptyCodeHash, account.CodeHash)
+
+		value, err := s.GetStorage(contract, common.BigToHash(big.NewInt(1)))
+		require.NoError(t, err)
+		require.Equal(t, common.BigToHash(big.NewInt(42)), value)
+	})
+
+	t.Run("should fail to parse an invalid alloc", func(t *testing.T) {
+		_, err := ParseGenesisAlloc([]byte(`{"0x01": {"balance": "abc"}}`))
+		require.Error(t, err)
+	})
+}
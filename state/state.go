@@ -0,0 +1,344 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/mpetrun5/merkle-patricia-trie/trie"
+)
+
+// EmptyCodeHash is the code hash of an account without code.
+var EmptyCodeHash = crypto.Keccak256(nil)
+
+// StateTrie is Ethereum's world state, a secure trie of the RLP encoded accounts
+// keyed by their addresses, where each account has its own secure trie of storage
+// slots, whose root hash is the storage hash of the account.
+type StateTrie struct {
+	db       storage.KeyValueStore
+	accounts *trie.SecureTrie
+	// storages holds the storage tries which were opened, their root hashes
+	// are folded back into the accounts when hashing or committing.
+	storages map[common.Address]*trie.SecureTrie
+	// codes holds the contract codes which are not written to the store yet.
+	codes map[common.Hash][]byte
+}
+
+// New opens the state with the given root hash from the key-value store.
+// The key-value store can be nil for a state which is only kept in memory,
+// which can't be committed.
+func New(rootHash []byte, db storage.KeyValueStore) (*StateTrie, error) {
+	accounts, err := trie.New(rootHash, db)
+	if err != nil {
+		return nil, fmt.Errorf("could not open state: %w", err)
+	}
+
+	return &StateTrie{
+		db:       db,
+		accounts: trie.NewSecureTrie(accounts, nil),
+		storages: make(map[common.Address]*trie.SecureTrie),
+		codes:    make(map[common.Hash][]byte),
+	}, nil
+}
+
+// Copy returns a copy of the state sharing the nodes of its tries, so that updates
+// can be applied to the copy and discarded without affecting the state.
+func (s *StateTrie) Copy() *StateTrie {
+	storages := make(map[common.Address]*trie.SecureTrie, len(s.storages))
+	for addr, st := range s.storages {
+		storages[addr] = st.Copy()
+	}
+	codes := make(map[common.Hash][]byte, len(s.codes))
+	for codeHash, code := range s.codes {
+		codes[codeHash] = code
+	}
+
+	return &StateTrie{
+		db:       s.db,
+		accounts: s.accounts.Copy(),
+		storages: storages,
+		codes:    codes,
+	}
+}
+
+// NewAccount returns an account without nonce, balance, storage and code.
+func NewAccount() *proof.Account {
+	return &proof.Account{
+		Balance:     new(big.Int),
+		StorageHash: node.EmptyNodeHash,
+		CodeHash:    EmptyCodeHash,
+	}
+}
+
+// GetAccount returns the account at the address, or nil if it doesn't exist.
+// If the storage trie of the account was opened, the storage hash is its root hash.
+func (s *StateTrie) GetAccount(addr common.Address) (*proof.Account, error) {
+	account, err := s.storedAccount(addr)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	if st, ok := s.storages[addr]; ok {
+		account.StorageHash = st.Hash()
+	}
+	return account, nil
+}
+
+// storedAccount returns the account at the address as it's stored in the account trie,
+// whose storage hash isn't updated yet with the opened storage trie.
+func (s *StateTrie) storedAccount(addr common.Address) (*proof.Account, error) {
+	encoded, found, err := s.accounts.Get(addr[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not get account %v: %w", addr.Hex(), err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var account proof.Account
+	if err := rlp.DecodeBytes(encoded, &account); err != nil {
+		return nil, fmt.Errorf("could not decode account %v: %w", addr.Hex(), err)
+	}
+	return &account, nil
+}
+
+// SetAccount stores the account at the address. If the storage hash of the account
+// differs from the root hash of the opened storage trie, it replaces the storage trie.
+func (s *StateTrie) SetAccount(addr common.Address, account *proof.Account) error {
+	encoded, err := rlp.EncodeToBytes(account)
+	if err != nil {
+		return fmt.Errorf("could not encode account %v: %w", addr.Hex(), err)
+	}
+
+	if err := s.accounts.Put(addr[:], encoded); err != nil {
+		return fmt.Errorf("could not put account %v: %w", addr.Hex(), err)
+	}
+	if st, ok := s.storages[addr]; ok && !bytes.Equal(st.Hash(), account.StorageHash) {
+		delete(s.storages, addr)
+	}
+	return nil
+}
+
+// DeleteAccount removes the account at the address along with its storage,
+// and returns whether it existed.
+func (s *StateTrie) DeleteAccount(addr common.Address) (bool, error) {
+	removed, err := s.accounts.Delete(addr[:])
+	if err != nil {
+		return false, fmt.Errorf("could not delete account %v: %w", addr.Hex(), err)
+	}
+	delete(s.storages, addr)
+	return removed, nil
+}
+
+// SetNonce sets the nonce of the account at the address, creating the account if it doesn't exist.
+func (s *StateTrie) SetNonce(addr common.Address, nonce uint64) error {
+	return s.updateAccount(addr, func(account *proof.Account) {
+		account.Nonce = nonce
+	})
+}
+
+// SetBalance sets the balance of the account at the address, creating the account if it doesn't exist.
+func (s *StateTrie) SetBalance(addr common.Address, balance *big.Int) error {
+	return s.updateAccount(addr, func(account *proof.Account) {
+		account.Balance = new(big.Int).Set(balance)
+	})
+}
+
+// SetCode sets the code of the account at the address, creating the account if it doesn't exist.
+// The account keeps the hash of the code, the code itself is written to the key-value
+// store under its hash on Commit.
+func (s *StateTrie) SetCode(addr common.Address, code []byte) error {
+	codeHash := crypto.Keccak256(code)
+	if len(code) > 0 {
+		s.codes[common.BytesToHash(codeHash)] = append([]byte{}, code...)
+	}
+
+	return s.updateAccount(addr, func(account *proof.Account) {
+		account.CodeHash = codeHash
+	})
+}
+
+// GetStorage returns the value of the storage slot of the account at the address,
+// which is zero if it's not set or the account doesn't exist.
+func (s *StateTrie) GetStorage(addr common.Address, slot common.Hash) (common.Hash, error) {
+	st, err := s.openStorage(addr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	encoded, found, err := st.Get(slot[:])
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("could not get storage %v of %v: %w", slot.Hex(), addr.Hex(), err)
+	}
+	if !found {
+		return common.Hash{}, nil
+	}
+
+	_, content, _, err := rlp.Split(encoded)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("could not decode storage %v of %v: %w", slot.Hex(), addr.Hex(), err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// SetStorage sets the value of the storage slot of the account at the address,
+// creating the account if it doesn't exist. Setting a slot to zero removes it.
+func (s *StateTrie) SetStorage(addr common.Address, slot common.Hash, value common.Hash) error {
+	account, err := s.GetAccount(addr)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		if err := s.SetAccount(addr, NewAccount()); err != nil {
+			return err
+		}
+	}
+
+	st, err := s.openStorage(addr)
+	if err != nil {
+		return err
+	}
+
+	// slots are stored as RLP encoded byte arrays without leading zeros
+	trimmed := common.TrimLeftZeroes(value[:])
+	if len(trimmed) == 0 {
+		if _, err := st.Delete(slot[:]); err != nil {
+			return fmt.Errorf("could not delete storage %v of %v: %w", slot.Hex(), addr.Hex(), err)
+		}
+		return nil
+	}
+
+	encoded, err := rlp.EncodeToBytes(trimmed)
+	if err != nil {
+		return err
+	}
+	if err := st.Put(slot[:], encoded); err != nil {
+		return fmt.Errorf("could not put storage %v of %v: %w", slot.Hex(), addr.Hex(), err)
+	}
+	return nil
+}
+
+// Hash folds the root hashes of the opened storage tries into their accounts,
+// and returns the root hash of the state.
+func (s *StateTrie) Hash() ([]byte, error) {
+	if err := s.updateStorageHashes(); err != nil {
+		return nil, err
+	}
+	return s.accounts.Hash(), nil
+}
+
+// Commit writes the storage tries, the contract codes and the account trie to the
+// key-value store, and returns the root hash of the state.
+func (s *StateTrie) Commit() ([]byte, error) {
+	if s.db == nil {
+		return nil, trie.ErrNoStore
+	}
+
+	for addr, st := range s.storages {
+		if _, err := st.Commit(); err != nil {
+			return nil, fmt.Errorf("could not commit storage of %v: %w", addr.Hex(), err)
+		}
+	}
+	if err := s.updateStorageHashes(); err != nil {
+		return nil, err
+	}
+
+	if len(s.codes) > 0 {
+		batch := s.db.NewBatch()
+		for codeHash, code := range s.codes {
+			if err := batch.Put(codeHash[:], code); err != nil {
+				return nil, fmt.Errorf("could not write code: %w", err)
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return nil, fmt.Errorf("could not write codes: %w", err)
+		}
+		s.codes = make(map[common.Hash][]byte)
+	}
+
+	return s.accounts.Commit()
+}
+
+// updateAccount applies the update to the account at the address, or to a new account
+// if it doesn't exist, and stores it.
+func (s *StateTrie) updateAccount(addr common.Address, update func(*proof.Account)) error {
+	account, err := s.GetAccount(addr)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		account = NewAccount()
+	}
+
+	update(account)
+
+	encoded, err := rlp.EncodeToBytes(account)
+	if err != nil {
+		return fmt.Errorf("could not encode account %v: %w", addr.Hex(), err)
+	}
+	if err := s.accounts.Put(addr[:], encoded); err != nil {
+		return fmt.Errorf("could not put account %v: %w", addr.Hex(), err)
+	}
+	return nil
+}
+
+// updateStorageHashes sets the storage hash of the accounts whose storage tries
+// were opened to the root hash of their storage tries.
+func (s *StateTrie) updateStorageHashes() error {
+	for addr, st := range s.storages {
+		account, err := s.storedAccount(addr)
+		if err != nil {
+			return err
+		}
+		// the storage of an account that doesn't exist was only read
+		if account == nil {
+			delete(s.storages, addr)
+			continue
+		}
+
+		storageHash := st.Hash()
+		if bytes.Equal(account.StorageHash, storageHash) {
+			continue
+		}
+
+		account.StorageHash = storageHash
+		encoded, err := rlp.EncodeToBytes(account)
+		if err != nil {
+			return fmt.Errorf("could not encode account %v: %w", addr.Hex(), err)
+		}
+		if err := s.accounts.Put(addr[:], encoded); err != nil {
+			return fmt.Errorf("could not put account %v: %w", addr.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// openStorage returns the storage trie of the account at the address,
+// which is opened from the storage hash of the account the first time.
+func (s *StateTrie) openStorage(addr common.Address) (*trie.SecureTrie, error) {
+	if st, ok := s.storages[addr]; ok {
+		return st, nil
+	}
+
+	account, err := s.GetAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	storageHash := node.EmptyNodeHash
+	if account != nil {
+		storageHash = account.StorageHash
+	}
+
+	t, err := trie.New(storageHash, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("could not open storage of %v: %w", addr.Hex(), err)
+	}
+	st := trie.NewSecureTrie(t, nil)
+	s.storages[addr] = st
+	return st, nil
+}
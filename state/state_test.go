@@ -0,0 +1,224 @@
+package state
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	mpt "github.com/mpetrun5/merkle-patricia-trie/trie"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	alice    = common.HexToAddress("0x01")
+	contract = common.HexToAddress("0x02")
+)
+
+func stateHash(t *testing.T, s *StateTrie) []byte {
+	hash, err := s.Hash()
+	require.NoError(t, err)
+	return hash
+}
+
+func newEthSecureTrie(t *testing.T) *trie.SecureTrie {
+	st, err := trie.NewSecure(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	require.NoError(t, err)
+	return st
+}
+
+func TestStateTrieMatchesEthState(t *testing.T) {
+	s, err := New(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.SetNonce(alice, 3))
+	require.NoError(t, s.SetBalance(alice, big.NewInt(1000)))
+
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	require.NoError(t, s.SetCode(contract, code))
+	require.NoError(t, s.SetNonce(contract, 1))
+	for i := int64(1); i <= 20; i++ {
+		require.NoError(t, s.SetStorage(contract, common.BigToHash(big.NewInt(i)), common.BigToHash(big.NewInt(i*100))))
+	}
+	// a slot set to zero is removed
+	require.NoError(t, s.SetStorage(contract, common.BigToHash(big.NewInt(5)), common.Hash{}))
+
+	ethStorage := newEthSecureTrie(t)
+	for i := int64(1); i <= 20; i++ {
+		if i == 5 {
+			continue
+		}
+		value, err := rlp.EncodeToBytes(big.NewInt(i * 100).Bytes())
+		require.NoError(t, err)
+		ethStorage.Update(common.BigToHash(big.NewInt(i)).Bytes(), value)
+	}
+	storageHash := ethStorage.Hash()
+
+	ethState := newEthSecureTrie(t)
+	for addr, account := range map[common.Address]*proof.Account{
+		alice:    {Nonce: 3, Balance: big.NewInt(1000), StorageHash: node.EmptyNodeHash, CodeHash: EmptyCodeHash},
+		contract: {Nonce: 1, Balance: new(big.Int), StorageHash: storageHash[:], CodeHash: crypto.Keccak256(code)},
+	} {
+		encoded, err := rlp.EncodeToBytes(account)
+		require.NoError(t, err)
+		ethState.Update(addr[:], encoded)
+	}
+
+	ethRoot := ethState.Hash()
+	require.Equal(t, ethRoot[:], stateHash(t, s))
+
+	account, err := s.GetAccount(contract)
+	require.NoError(t, err)
+	require.Equal(t, storageHash[:], account.StorageHash)
+
+	value, err := s.GetStorage(contract, common.BigToHash(big.NewInt(7)))
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(700)), value)
+
+	value, err = s.GetStorage(contract, common.BigToHash(big.NewInt(5)))
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{}, value)
+}
+
+func TestStateTrieAccountRoundTrip(t *testing.T) {
+	db := storage.NewMemoryStore()
+	s, err := New(nil, db)
+	require.NoError(t, err)
+	slot := common.BigToHash(big.NewInt(1))
+	require.NoError(t, s.SetStorage(contract, slot, common.BigToHash(big.NewInt(7))))
+
+	// the account read before hashing has the storage hash of the pending storage
+	account, err := s.GetAccount(contract)
+	require.NoError(t, err)
+	account.Nonce = 1
+	require.NoError(t, s.SetAccount(contract, account))
+
+	value, err := s.GetStorage(contract, slot)
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(7)), value)
+
+	root, err := s.Commit()
+	require.NoError(t, err)
+	reopened, err := New(root, db)
+	require.NoError(t, err)
+	value, err = reopened.GetStorage(contract, slot)
+	require.NoError(t, err)
+	require.Equal(t, common.BigToHash(big.NewInt(7)), value)
+
+	// another storage hash replaces the storage
+	account.StorageHash = node.EmptyNodeHash
+	require.NoError(t, reopened.SetAccount(contract, account))
+	value, err = reopened.GetStorage(contract, slot)
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{}, value)
+}
+
+func TestStateTrie(t *testing.T) {
+	t.Run("should get nothing for an account that doesn't exist", func(t *testing.T) {
+		s, err := New(nil, nil)
+		require.NoError(t, err)
+
+		account, err := s.GetAccount(alice)
+		require.NoError(t, err)
+		require.Nil(t, account)
+
+		value, err := s.GetStorage(alice, common.Hash{})
+		require.NoError(t, err)
+		require.Equal(t, common.Hash{}, value)
+		require.Equal(t, node.EmptyNodeHash, stateHash(t, s))
+	})
+
+	t.Run("should delete an account with its storage", func(t *testing.T) {
+		s, err := New(nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, s.SetBalance(alice, big.NewInt(1)))
+		empty := stateHash(t, s)
+
+		require.NoError(t, s.SetStorage(contract, common.Hash{1}, common.Hash{2}))
+		require.NotEqual(t, empty, stateHash(t, s))
+
+		removed, err := s.DeleteAccount(contract)
+		require.NoError(t, err)
+		require.True(t, removed)
+		removed, err = s.DeleteAccount(contract)
+		require.NoError(t, err)
+		require.False(t, removed)
+		require.Equal(t, empty, stateHash(t, s))
+
+		value, err := s.GetStorage(contract, common.Hash{1})
+		require.NoError(t, err)
+		require.Equal(t, common.Hash{}, value)
+	})
+
+	t.Run("should fail to commit a state without key-value store", func(t *testing.T) {
+		s, err := New(nil, nil)
+		require.NoError(t, err)
+
+		_, err = s.Commit()
+		require.True(t, errors.Is(err, mpt.ErrNoStore), err)
+	})
+
+	t.Run("should reopen a committed state", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		s, err := New(nil, db)
+		require.NoError(t, err)
+
+		code := []byte{0x60, 0x00}
+		require.NoError(t, s.SetCode(contract, code))
+		for i := byte(1); i <= 10; i++ {
+			require.NoError(t, s.SetStorage(contract, common.Hash{i}, common.Hash{0, i}))
+		}
+
+		root, err := s.Commit()
+		require.NoError(t, err)
+		require.Equal(t, stateHash(t, s), root)
+
+		stored, err := db.Get(crypto.Keccak256(code))
+		require.NoError(t, err)
+		require.Equal(t, code, stored)
+
+		reopened, err := New(root, db)
+		require.NoError(t, err)
+		value, err := reopened.GetStorage(contract, common.Hash{3})
+		require.NoError(t, err)
+		require.Equal(t, common.Hash{0, 3}, value)
+
+		// updating the storage of the reopened state changes the root
+		require.NoError(t, reopened.SetStorage(contract, common.Hash{3}, common.Hash{}))
+		updated, err := reopened.Commit()
+		require.NoError(t, err)
+		require.NotEqual(t, root, updated)
+
+		// and is the same as updating the original state
+		require.NoError(t, s.SetStorage(contract, common.Hash{3}, common.Hash{}))
+		require.Equal(t, updated, stateHash(t, s))
+	})
+}
+
+func TestStateTrieCopy(t *testing.T) {
+	s, err := New(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.SetBalance(alice, big.NewInt(1000)))
+	require.NoError(t, s.SetStorage(contract, common.Hash{1}, common.Hash{2}))
+	hash := stateHash(t, s)
+
+	copied := s.Copy()
+	require.NoError(t, copied.SetBalance(alice, big.NewInt(1)))
+	require.NoError(t, copied.SetStorage(contract, common.Hash{1}, common.Hash{3}))
+	require.NotEqual(t, hash, stateHash(t, copied))
+	require.Equal(t, hash, stateHash(t, s))
+
+	value, err := s.GetStorage(contract, common.Hash{1})
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{2}, value)
+
+	account, err := s.GetAccount(alice)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1000), account.Balance)
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryStore is a KeyValueStore keeping the data in memory.
+// It's safe for concurrent use.
+type MemoryStore struct {
+	lock sync.RWMutex
+	kv   map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		kv: make(map[string][]byte),
+	}
+}
+
+func (m *MemoryStore) Has(key []byte) (bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	_, ok := m.kv[string(key)]
+	return ok, nil
+}
+
+func (m *MemoryStore) Get(key []byte) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	value, ok := m.kv[string(key)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %x", ErrNotFound, key)
+	}
+	return copyBytes(value), nil
+}
+
+func (m *MemoryStore) Put(key []byte, value []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.kv[string(key)] = copyBytes(value)
+	return nil
+}
+
+func (m *MemoryStore) Delete(key []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.kv, string(key))
+	return nil
+}
+
+// Len returns the number of keys in the store.
+func (m *MemoryStore) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return len(m.kv)
+}
+
+func (m *MemoryStore) NewBatch() Batch {
+	return &memoryBatch{db: m}
+}
+
+type write struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+type memoryBatch struct {
+	db     *MemoryStore
+	writes []write
+}
+
+func (b *memoryBatch) Put(key []byte, value []byte) error {
+	b.writes = append(b.writes, write{key: copyBytes(key), value: copyBytes(value)})
+	return nil
+}
+
+func (b *memoryBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, write{key: copyBytes(key), delete: true})
+	return nil
+}
+
+func (b *memoryBatch) Len() int {
+	return len(b.writes)
+}
+
+func (b *memoryBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	for _, w := range b.writes {
+		if w.delete {
+			delete(b.db.kv, string(w.key))
+		} else {
+			b.db.kv[string(w.key)] = w.value
+		}
+	}
+	return nil
+}
+
+func (b *memoryBatch) Reset() {
+	b.writes = b.writes[:0]
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	db := NewMemoryStore()
+
+	_, err := db.Get([]byte("key"))
+	require.True(t, errors.Is(err, ErrNotFound), err)
+
+	value := []byte("value")
+	require.NoError(t, db.Put([]byte("key"), value))
+	// the stored value should not be affected by changes to the given slice
+	value[0] = 'V'
+
+	got, err := db.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+
+	has, err := db.Has([]byte("key"))
+	require.NoError(t, err)
+	require.True(t, has)
+
+	require.NoError(t, db.Delete([]byte("key")))
+	has, err = db.Has([]byte("key"))
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestMemoryBatch(t *testing.T) {
+	db := NewMemoryStore()
+	require.NoError(t, db.Put([]byte("deleted"), []byte("value")))
+
+	batch := db.NewBatch()
+	require.NoError(t, batch.Put([]byte("key1"), []byte("value1")))
+	require.NoError(t, batch.Put([]byte("key2"), []byte("value2")))
+	require.NoError(t, batch.Delete([]byte("deleted")))
+	require.Equal(t, 3, batch.Len())
+
+	// nothing is written before the batch is written
+	require.Equal(t, 1, db.Len())
+
+	require.NoError(t, batch.Write())
+	require.Equal(t, 2, db.Len())
+
+	got, err := db.Get([]byte("key2"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value2"), got)
+
+	batch.Reset()
+	require.Equal(t, 0, batch.Len())
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"errors"
+)
+
+// ErrNotFound is returned when a key is not in the key-value store.
+var ErrNotFound = errors.New("not found")
+
+// KeyValueStore is the persistent storage of trie nodes.
+type KeyValueStore interface {
+	// Has retrieves if a key is present in the key-value store.
+	Has(key []byte) (bool, error)
+
+	// Get retrieves the given key if it's present in the key-value store,
+	// otherwise ErrNotFound is returned.
+	Get(key []byte) ([]byte, error)
+
+	// Put inserts the given value into the key-value store.
+	Put(key []byte, value []byte) error
+
+	// Delete removes the key from the key-value store.
+	Delete(key []byte) error
+
+	// NewBatch creates a batch to write to the key-value store at once.
+	NewBatch() Batch
+}
+
+// Batch collects writes in memory, until Write applies them to the key-value store.
+type Batch interface {
+	// Put inserts the given value into the key-value store when the batch is written.
+	Put(key []byte, value []byte) error
+
+	// Delete removes the key from the key-value store when the batch is written.
+	Delete(key []byte) error
+
+	// Len returns the number of writes in the batch.
+	Len() int
+
+	// Write applies the batch to the key-value store.
+	Write() error
+
+	// Reset discards the writes in the batch, so it can be reused.
+	Reset()
+}
@@ -0,0 +1,143 @@
+package trie
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// KeyValue is a key-value pair to put in a trie.
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// batchItem is a key-value pair with the path of the key which remains below a node.
+type batchItem struct {
+	path  nibble.Path
+	value []byte
+}
+
+// PutBatch adds the key-value pairs to the trie, which is the same as putting them one
+// by one in the given order, but the pairs are sorted so that each node on their paths
+// is only visited and copied once, instead of once per key.
+// The trie is not updated if an error is returned because a node can't be loaded.
+func (t *Trie) PutBatch(kvs []KeyValue) error {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	sorted := make([]KeyValue, len(kvs))
+	copy(sorted, kvs)
+	// a stable sort keeps the pairs with the same key in the given order,
+	// so that the last one wins, as if they were put one by one.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0
+	})
+
+	items := make([]batchItem, 0, len(sorted))
+	for i, kv := range sorted {
+		if i+1 < len(sorted) && bytes.Equal(kv.Key, sorted[i+1].Key) {
+			continue
+		}
+		items = append(items, batchItem{path: nibble.PathFromBytes(kv.Key), value: kv.Value})
+	}
+
+	root, err := t.insertBatch(t.root, items)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// insertBatch adds the sorted items with distinct keys under the given node, and returns
+// the node that should replace it. At a branch node, the items are partitioned by their
+// next nibble, so that each child is updated once with all of its items.
+func (t *Trie) insertBatch(n node.Node, items []batchItem) (node.Node, error) {
+	if len(items) == 1 {
+		return t.insert(n, items[0].path, items[0].value)
+	}
+
+	if hash, ok := n.(node.HashNode); ok {
+		resolved, err := t.resolve(hash)
+		if err != nil {
+			return nil, err
+		}
+		n = resolved
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		branch = branch.Copy()
+		// the item for the path of the branch node, if any, is the first one
+		if items[0].path.Len() == 0 {
+			branch.SetValue(items[0].value)
+			items = items[1:]
+		}
+
+		for len(items) > 0 {
+			b := items[0].path.At(0)
+			end := 1
+			for end < len(items) && items[end].path.At(0) == b {
+				end++
+			}
+
+			children := make([]batchItem, end)
+			for i, item := range items[:end] {
+				children[i] = batchItem{path: item.path.Slice(1, item.path.Len()), value: item.value}
+			}
+			child, err := t.insertBatch(branch.Branches[b], children)
+			if err != nil {
+				return nil, err
+			}
+			branch.SetBranch(b, child)
+			items = items[end:]
+		}
+		return branch, nil
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		diverging := -1
+		for i, item := range items {
+			if ext.Path.MatchedLen(item.path) < ext.Path.Len() {
+				diverging = i
+				break
+			}
+		}
+
+		if diverging < 0 {
+			nexts := make([]batchItem, len(items))
+			for i, item := range items {
+				nexts[i] = batchItem{path: item.path.Slice(ext.Path.Len(), item.path.Len()), value: item.value}
+			}
+			next, err := t.insertBatch(ext.Next, nexts)
+			if err != nil {
+				return nil, err
+			}
+			ext = ext.Copy()
+			ext.SetNext(next)
+			return ext, nil
+		}
+
+		// the diverging item turns the extension node into a branch node,
+		// or an extension node with a shorter path above a branch node.
+		updated, err := t.insert(ext, items[diverging].path, items[diverging].value)
+		if err != nil {
+			return nil, err
+		}
+		rest := make([]batchItem, 0, len(items)-1)
+		rest = append(rest, items[:diverging]...)
+		rest = append(rest, items[diverging+1:]...)
+		return t.insertBatch(updated, rest)
+	}
+
+	// the node is empty or a leaf node, the first item turns it into a leaf node,
+	// or a branch node, or an extension node above a branch node.
+	updated, err := t.insert(n, items[0].path, items[0].value)
+	if err != nil {
+		return nil, err
+	}
+	return t.insertBatch(updated, items[1:])
+}
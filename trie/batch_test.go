@@ -0,0 +1,131 @@
+package trie
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutBatch(t *testing.T) {
+	t.Run("should get the same root hash as putting the keys one by one", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		for _, n := range []int{1, 2, 10, 100, 2000} {
+			// short random keys, some of them are prefixes of others, in random order
+			sequential, _, _ := randomTrie(rnd, n)
+			kvs := keyValuesOf(sequential)
+			rnd.Shuffle(len(kvs), func(i, j int) { kvs[i], kvs[j] = kvs[j], kvs[i] })
+
+			batch := NewTrie()
+			require.NoError(t, batch.PutBatch(kvs))
+			require.Equal(t, sequential.Hash(), batch.Hash())
+
+			for _, kv := range kvs {
+				expected, _, err := sequential.Get(kv.Key)
+				require.NoError(t, err)
+				value, found, err := batch.Get(kv.Key)
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, expected, value)
+			}
+		}
+	})
+
+	t.Run("should keep the last value of a duplicated key", func(t *testing.T) {
+		tr := NewTrie()
+		require.NoError(t, tr.PutBatch([]KeyValue{
+			{Key: []byte("key"), Value: []byte("first")},
+			{Key: []byte("other"), Value: []byte("other")},
+			{Key: []byte("key"), Value: []byte("last")},
+		}))
+
+		value, found, err := tr.Get([]byte("key"))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("last"), value)
+	})
+
+	t.Run("should update a trie loaded from the key-value store", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(2))
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		expected, _, _ := randomTrie(rnd, 500)
+		require.NoError(t, tr.PutBatch(keyValuesOf(expected)))
+		root, err := tr.Commit()
+		require.NoError(t, err)
+
+		updates, _, _ := randomTrie(rnd, 500)
+		kvs := keyValuesOf(updates)
+		require.NoError(t, expected.PutBatch(kvs))
+
+		loaded, err := New(root, db)
+		require.NoError(t, err)
+		require.NoError(t, loaded.PutBatch(kvs))
+		require.Equal(t, expected.Hash(), loaded.Hash())
+	})
+
+	t.Run("should not change the trie if a node is missing", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(tr, 500)
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+
+		// the keys share the prefix "key", so the root node is an extension node
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		root, ok := reopened.root.(*node.ExtensionNode)
+		require.True(t, ok)
+		next, ok := root.Next.(node.HashNode)
+		require.True(t, ok)
+		require.NoError(t, db.Delete(next))
+
+		err = reopened.PutBatch([]KeyValue{
+			{Key: []byte("a"), Value: []byte("a")},
+			{Key: []byte("key42"), Value: []byte("updated")},
+		})
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+		require.Equal(t, hash, reopened.Hash())
+	})
+
+	t.Run("should not change a copy of the trie", func(t *testing.T) {
+		tr := NewTrie()
+		putKeys(tr, 100)
+		copied := tr.Copy()
+		hash := copied.Hash()
+
+		updates, _, _ := randomTrie(rand.New(rand.NewSource(3)), 100)
+		require.NoError(t, tr.PutBatch(keyValuesOf(updates)))
+		require.NotEqual(t, hash, tr.Hash())
+		require.Equal(t, hash, copied.Hash())
+	})
+}
+
+func BenchmarkPutBatch(b *testing.B) {
+	keys := hashedKeys(100000)
+	kvs := make([]KeyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = KeyValue{Key: key, Value: key}
+	}
+
+	b.Run("put", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tr := NewTrie()
+			for _, kv := range kvs {
+				tr.Put(kv.Key, kv.Value)
+			}
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tr := NewTrie()
+			tr.PutBatch(kvs)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+)
+
+// ErrUnsortedKeys is returned when the keys given to a Builder are not strictly increasing.
+var ErrUnsortedKeys = errors.New("keys are not sorted")
+
+// builderFlushSize is the number of nodes a Builder writes to the key-value store at once.
+const builderFlushSize = 1024
+
+// KeyValueIterator iterates over key-value pairs, such as an Iterator of a trie.
+type KeyValueIterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Err() error
+}
+
+// Builder builds a trie from key-value pairs added in increasing order of the keys,
+// in a single pass. Since no key added later can be to the left of the path of the last key,
+// the sub tries to the left of it are complete: they are hashed, written to the key-value store
+// if there is one, and replaced by their hashes. So the builder only keeps the nodes on the
+// path of the last key in memory, which allows to build tries too large to fit in memory.
+type Builder struct {
+	trie  *Trie
+	batch storage.Batch
+	last  nibble.Path
+	count int
+}
+
+// NewBuilder returns a builder writing the nodes to the key-value store,
+// which can be nil to only compute the root hash. The options configure the built trie.
+func NewBuilder(db storage.KeyValueStore, opts ...Option) *Builder {
+	b := &Builder{trie: NewTrie(opts...)}
+	if db != nil {
+		b.trie.db = db
+		b.batch = db.NewBatch()
+	}
+	return b
+}
+
+// Add adds the key-value pair, the key must be greater than the keys added before.
+func (b *Builder) Add(key []byte, value []byte) error {
+	path := nibble.PathFromBytes(key)
+	if b.count > 0 && b.last.Compare(path) >= 0 {
+		return fmt.Errorf("%w: %x after %x", ErrUnsortedKeys, key, b.lastKey())
+	}
+
+	root, err := b.trie.insert(b.trie.root, path, value)
+	if err != nil {
+		return err
+	}
+	b.trie.root = root
+
+	if b.count > 0 {
+		// the sub trie of the last key which is to the left of the new key is complete
+		matched := b.last.MatchedLen(path)
+		if matched < b.last.Len() {
+			if err := b.fold(matched); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.last = path
+	b.count++
+	return nil
+}
+
+// fold replaces the child of the branch node at the given depth on the path of the last key,
+// which is the branch node where the paths of the last key and the new key diverge,
+// with its hash, after writing it and its descendants to the key-value store.
+func (b *Builder) fold(depth int) error {
+	n := b.trie.root
+	path := b.last
+	for depth > 0 {
+		if branch, ok := n.(*node.BranchNode); ok {
+			n, path, depth = branch.Branches[path.At(0)], path.Slice(1, path.Len()), depth-1
+			continue
+		}
+		if ext, ok := n.(*node.ExtensionNode); ok {
+			n, path, depth = ext.Next, path.Slice(ext.Path.Len(), path.Len()), depth-ext.Path.Len()
+			continue
+		}
+		return fmt.Errorf("%w: no branch node at the divergence of %x", node.ErrCorruptNode, b.lastKey())
+	}
+
+	branch, ok := n.(*node.BranchNode)
+	if !ok || depth < 0 {
+		return fmt.Errorf("%w: no branch node at the divergence of %x", node.ErrCorruptNode, b.lastKey())
+	}
+
+	child := branch.Branches[path.At(0)]
+	if b.batch != nil {
+		if err := commit(child, b.batch, b.trie.hasher, false); err != nil {
+			return err
+		}
+		if b.batch.Len() >= builderFlushSize {
+			if err := b.batch.Write(); err != nil {
+				return fmt.Errorf("could not write batch: %w", err)
+			}
+			b.batch.Reset()
+		}
+	}
+
+	// a node serialized to less than the size of a hash is embedded in its parent,
+	// it's kept as is
+	serialized, err := node.SerializeWith(b.trie.hasher, child)
+	if err != nil {
+		return err
+	}
+	if len(serialized) >= b.trie.hasher.Size() {
+		// the branch node is on the path of the last key, it was copied when it was
+		// inserted, so it's only referenced by the builder.
+		branch.SetBranch(path.At(0), node.HashNode(node.HashWith(b.trie.hasher, child)))
+	}
+	return nil
+}
+
+// Hash returns the root hash of the key-value pairs added so far.
+func (b *Builder) Hash() []byte {
+	return b.trie.Hash()
+}
+
+// Finish writes the remaining nodes to the key-value store, and returns the trie.
+// The sub tries which were folded are only referenced by their hashes, so they can
+// only be loaded if the builder has a key-value store.
+func (b *Builder) Finish() (*Trie, error) {
+	if b.batch != nil {
+		// the folded sub tries are written before the root node, so that the store
+		// never holds a root node whose descendants are missing.
+		if err := b.batch.Write(); err != nil {
+			return nil, fmt.Errorf("could not write batch: %w", err)
+		}
+		b.batch.Reset()
+		if _, err := b.trie.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return b.trie, nil
+}
+
+func (b *Builder) lastKey() []byte {
+	key, _ := nibble.ToBytes(b.last.Nibbles())
+	return key
+}
+
+// BuildFromSorted builds a trie from the key-value pairs of the iterator, whose keys must be
+// sorted, with a Builder. The nodes are written to the key-value store, which can be nil to
+// only compute the root hash of the trie.
+func BuildFromSorted(it KeyValueIterator, db storage.KeyValueStore, opts ...Option) (*Trie, error) {
+	b := NewBuilder(db, opts...)
+	for it.Next() {
+		if err := b.Add(it.Key(), it.Value()); err != nil {
+			return nil, err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate: %w", err)
+	}
+	return b.Finish()
+}
+
+// sortedPairs iterates over key-value pairs in a slice.
+type sortedPairs struct {
+	kvs   []KeyValue
+	index int
+}
+
+// NewSliceIterator returns an iterator over the key-value pairs, in the given order.
+func NewSliceIterator(kvs []KeyValue) KeyValueIterator {
+	return &sortedPairs{kvs: kvs, index: -1}
+}
+
+func (s *sortedPairs) Next() bool {
+	s.index++
+	return s.index < len(s.kvs)
+}
+
+func (s *sortedPairs) Key() []byte {
+	return s.kvs[s.index].Key
+}
+
+func (s *sortedPairs) Value() []byte {
+	return s.kvs[s.index].Value
+}
+
+func (s *sortedPairs) Err() error {
+	return nil
+}
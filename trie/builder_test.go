@@ -0,0 +1,149 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// keyValuesOf returns the key-value pairs of the trie sorted by key
+func keyValuesOf(tr *Trie) []KeyValue {
+	var sorted []KeyValue
+	it := tr.NewIterator(nil)
+	for it.Next() {
+		sorted = append(sorted, KeyValue{Key: it.Key(), Value: it.Value()})
+	}
+	return sorted
+}
+
+func TestBuildFromSorted(t *testing.T) {
+	t.Run("should get the same root hash as putting the keys", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		for _, n := range []int{0, 1, 2, 10, 100, 2000} {
+			expected, _, _ := randomTrie(rnd, n)
+			kvs := keyValuesOf(expected)
+
+			built, err := BuildFromSorted(NewSliceIterator(kvs), nil)
+			require.NoError(t, err)
+			require.Equal(t, expected.Hash(), built.Hash())
+		}
+	})
+
+	t.Run("should build the trie of hashed keys", func(t *testing.T) {
+		keys := hashedKeys(10000)
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+
+		expected := NewTrie()
+		b := NewBuilder(nil)
+		for _, key := range keys {
+			require.NoError(t, expected.Put(key, key))
+			require.NoError(t, b.Add(key, key))
+		}
+		require.Equal(t, expected.Hash(), b.Hash())
+	})
+
+	t.Run("should write the trie to the key-value store", func(t *testing.T) {
+		tr, _, _ := randomTrie(rand.New(rand.NewSource(2)), 2000)
+		kvs := keyValuesOf(tr)
+		db := storage.NewMemoryStore()
+		built, err := BuildFromSorted(NewSliceIterator(kvs), db)
+		require.NoError(t, err)
+
+		reopened, err := New(built.Hash(), db)
+		require.NoError(t, err)
+		for _, kv := range kvs {
+			value, found, err := reopened.Get(kv.Key)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, kv.Value, value)
+
+			// the built trie loads the folded sub tries from the store
+			value, found, err = built.Get(kv.Key)
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, kv.Value, value)
+		}
+	})
+
+	t.Run("should build from the iterator of another trie", func(t *testing.T) {
+		tr, _, _ := randomTrie(rand.New(rand.NewSource(3)), 1000)
+		built, err := BuildFromSorted(tr.NewIterator(nil), nil)
+		require.NoError(t, err)
+		require.Equal(t, tr.Hash(), built.Hash())
+	})
+
+	t.Run("should fail if the keys are not sorted", func(t *testing.T) {
+		b := NewBuilder(nil)
+		require.NoError(t, b.Add([]byte("b"), []byte("b")))
+		err := b.Add([]byte("a"), []byte("a"))
+		require.True(t, errors.Is(err, ErrUnsortedKeys), err)
+		err = b.Add([]byte("b"), []byte("b"))
+		require.True(t, errors.Is(err, ErrUnsortedKeys), err)
+
+		_, err = BuildFromSorted(NewSliceIterator([]KeyValue{
+			{Key: []byte("key2"), Value: []byte("value")},
+			{Key: []byte("key1"), Value: []byte("value")},
+		}), nil)
+		require.True(t, errors.Is(err, ErrUnsortedKeys), err)
+	})
+}
+
+// failingStore fails to write the first batch created from it.
+type failingStore struct {
+	storage.KeyValueStore
+	batches int
+}
+
+func (s *failingStore) NewBatch() storage.Batch {
+	s.batches++
+	if s.batches == 1 {
+		return failingBatch{s.KeyValueStore.NewBatch()}
+	}
+	return s.KeyValueStore.NewBatch()
+}
+
+type failingBatch struct {
+	storage.Batch
+}
+
+func (failingBatch) Write() error {
+	return errors.New("write failed")
+}
+
+func TestBuilderWriteError(t *testing.T) {
+	// the batch of the builder, holding the folded sub tries, fails to be written
+	db := &failingStore{KeyValueStore: storage.NewMemoryStore()}
+	b := NewBuilder(db)
+	tr, _, _ := randomTrie(rand.New(rand.NewSource(1)), 500)
+	for _, kv := range keyValuesOf(tr) {
+		require.NoError(t, b.Add(kv.Key, kv.Value))
+	}
+
+	_, err := b.Finish()
+	require.Error(t, err)
+
+	// the root node isn't written either, so the incomplete trie can't be opened
+	_, err = New(b.Hash(), db)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+}
+
+func BenchmarkBuildFromSorted(b *testing.B) {
+	keys := hashedKeys(100000)
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	kvs := make([]KeyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = KeyValue{Key: key, Value: key}
+	}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildFromSorted(NewSliceIterator(kvs), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
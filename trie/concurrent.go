@@ -0,0 +1,136 @@
+package trie
+
+import (
+	"sync"
+
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+)
+
+// ConcurrentTrie is a trie which is safe for concurrent use. Readers are not blocked
+// by a writer: they use the last published version of the trie, while the writer builds
+// the next version. Since the nodes of a trie are copied before they are updated, the
+// versions share the nodes which were not updated, and a published version is never modified.
+type ConcurrentTrie struct {
+	// writeLock serializes the writers.
+	writeLock sync.Mutex
+	// lock guards the published version, it's only held to get or replace it.
+	lock sync.RWMutex
+	trie *Trie
+}
+
+// NewConcurrentTrie returns a concurrency-safe trie starting as the given trie,
+// which must not be used directly any more.
+func NewConcurrentTrie(t *Trie) *ConcurrentTrie {
+	// hash the trie before it's published, so that readers find the hashes memoized
+	t.Hash()
+	return &ConcurrentTrie{trie: t}
+}
+
+// current returns the last published version of the trie, which must not be updated.
+func (c *ConcurrentTrie) current() *Trie {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.trie
+}
+
+func (c *ConcurrentTrie) publish(t *Trie) {
+	t.Hash()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.trie = t
+}
+
+// next returns a version of the trie to be updated by the writer holding the write lock.
+func (c *ConcurrentTrie) next() *Trie {
+	return c.current().Copy()
+}
+
+// Snapshot returns the last published version of the trie, so that several
+// reads are done on the same version.
+func (c *ConcurrentTrie) Snapshot() *Snapshot {
+	return c.current().Snapshot()
+}
+
+func (c *ConcurrentTrie) Hash() []byte {
+	return c.current().Hash()
+}
+
+func (c *ConcurrentTrie) Get(key []byte) ([]byte, bool, error) {
+	return c.current().Get(key)
+}
+
+func (c *ConcurrentTrie) Prove(key []byte) (proof.Proof, bool, error) {
+	return c.current().Prove(key)
+}
+
+func (c *ConcurrentTrie) ProveMany(keys [][]byte) (proof.Proof, []bool, error) {
+	return c.current().ProveMany(keys)
+}
+
+func (c *ConcurrentTrie) ProveRange(first []byte, last []byte) (proof.Proof, error) {
+	return c.current().ProveRange(first, last)
+}
+
+// NewIterator returns an iterator over the version of the trie published when
+// it's created, which isn't affected by later updates.
+func (c *ConcurrentTrie) NewIterator(start []byte) *Iterator {
+	return c.current().NewIterator(start)
+}
+
+// ScanPrefix scans the version of the trie published when it's called, which isn't
+// affected by the updates made while scanning.
+func (c *ConcurrentTrie) ScanPrefix(prefix []byte, fn func(key, value []byte) error) error {
+	return c.current().ScanPrefix(prefix, fn)
+}
+
+// Put adds the key-value pair, the update is visible to readers once it returns.
+func (c *ConcurrentTrie) Put(key []byte, value []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	t := c.next()
+	if err := t.Put(key, value); err != nil {
+		return err
+	}
+	c.publish(t)
+	return nil
+}
+
+// Delete removes the key, the update is visible to readers once it returns.
+func (c *ConcurrentTrie) Delete(key []byte) (bool, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	t := c.next()
+	removed, err := t.Delete(key)
+	if err != nil || !removed {
+		return false, err
+	}
+	c.publish(t)
+	return true, nil
+}
+
+// DeletePrefix removes the keys starting with the prefix, the update is visible to
+// readers once it returns.
+func (c *ConcurrentTrie) DeletePrefix(prefix []byte) (bool, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	t := c.next()
+	removed, err := t.DeletePrefix(prefix)
+	if err != nil || !removed {
+		return false, err
+	}
+	c.publish(t)
+	return true, nil
+}
+
+// Commit writes the published version of the trie to its key-value store.
+// Readers are not blocked, but writers wait for the commit.
+func (c *ConcurrentTrie) Commit() ([]byte, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	return c.current().Commit()
+}
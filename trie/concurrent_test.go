@@ -0,0 +1,190 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// run with -race to detect data races between readers and the writer
+func TestConcurrentTrie(t *testing.T) {
+	c := NewConcurrentTrie(NewTrie())
+	const n = 500
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < n; i++ {
+			if err := c.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))); err != nil {
+				errs <- err
+				return
+			}
+			if i%3 == 0 {
+				if _, err := c.Delete([]byte(fmt.Sprintf("key%d", i/2))); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				key := []byte(fmt.Sprintf("key%d", (i*7+r)%n))
+				val, found, err := c.Get(key)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if found && string(val) != "value"+string(key[3:]) {
+					errs <- fmt.Errorf("unexpected value %s for %s", val, key)
+					return
+				}
+
+				// a proof must be valid for the root hash of the same version
+				version := c.Snapshot()
+				rootHash := version.Hash()
+				p, found, err := version.Prove(key)
+				if err != nil {
+					errs <- err
+					return
+				}
+				val, ok, err := proof.VerifyProof(rootHash, key, p)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if ok != found {
+					errs <- fmt.Errorf("proof of %s doesn't match: %v", key, val)
+					return
+				}
+
+				c.Hash()
+			}
+		}(r)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	expected := NewTrie()
+	for i := 0; i < n; i++ {
+		expected.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+		if i%3 == 0 {
+			expected.Delete([]byte(fmt.Sprintf("key%d", i/2)))
+		}
+	}
+	require.Equal(t, expected.Hash(), c.Hash())
+}
+
+func TestConcurrentTrieVersions(t *testing.T) {
+	t.Run("should not change an iterator's version when updated", func(t *testing.T) {
+		tr := NewTrie()
+		putKeys(tr, 10)
+		c := NewConcurrentTrie(tr)
+
+		it := c.NewIterator(nil)
+		require.NoError(t, c.Put([]byte("key5"), []byte("updated")))
+		removed, err := c.Delete([]byte("key0"))
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		keys, values := collect(t, it)
+		require.Len(t, keys, 10)
+		require.Equal(t, []byte("key0"), keys[0])
+		require.Equal(t, []byte("value5"), values[5])
+
+		val, found, err := c.Get([]byte("key5"))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("updated"), val)
+	})
+
+	t.Run("should not publish a failed update", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(tr, 100)
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+
+		// every node but the root node is missing
+		partial := storage.NewMemoryStore()
+		root, err := db.Get(hash)
+		require.NoError(t, err)
+		require.NoError(t, partial.Put(hash, root))
+
+		reopened, err := New(hash, partial)
+		require.NoError(t, err)
+		c := NewConcurrentTrie(reopened)
+
+		err = c.Put([]byte("key1"), []byte("updated"))
+		require.Error(t, err)
+		require.Equal(t, hash, c.Hash())
+
+		committed, err := c.Commit()
+		require.NoError(t, err)
+		require.Equal(t, hash, committed)
+	})
+}
+
+func TestConcurrentTrieCommit(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+	c := NewConcurrentTrie(tr)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 200; i += 4 {
+				require.NoError(t, c.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))))
+				if i%50 == 0 {
+					_, err := c.Commit()
+					require.NoError(t, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	hash, err := c.Commit()
+	require.NoError(t, err)
+
+	expected := NewTrie()
+	putKeys(expected, 200)
+	require.Equal(t, expected.Hash(), hash)
+
+	reopened, err := New(hash, db)
+	require.NoError(t, err)
+	_, found, err := reopened.Get([]byte("key199"))
+	require.NoError(t, err)
+	require.True(t, found)
+
+	_, err = NewConcurrentTrie(NewTrie()).Commit()
+	require.True(t, errors.Is(err, ErrNoStore), err)
+}
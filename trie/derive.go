@@ -0,0 +1,73 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+)
+
+// ErrItemNotIncluded is returned when a proof shows that there is no item at an index.
+var ErrItemNotIncluded = errors.New("item is not included")
+
+// IndexKey returns the key of the item at index i in a list trie,
+// which is the RLP encoding of the index.
+func IndexKey(i int) []byte {
+	key, err := rlp.EncodeToBytes(uint(i))
+	if err != nil {
+		// encoding an unsigned integer can't fail
+		panic(err)
+	}
+	return key
+}
+
+// DeriveTrie builds the trie of an ordered list of RLP encoded items, such as the
+// transactions, receipts or withdrawals of a block, where each item is keyed by its index.
+func DeriveTrie(items [][]byte) (*Trie, error) {
+	t := NewTrie()
+	for i, item := range items {
+		if err := t.Put(IndexKey(i), item); err != nil {
+			return nil, fmt.Errorf("could not put item %v: %w", i, err)
+		}
+	}
+	return t, nil
+}
+
+// DeriveSha returns the root hash of the trie of the ordered list of RLP encoded items,
+// which is the transactionsRoot, receiptsRoot or withdrawalsRoot of a block header.
+func DeriveSha(items [][]byte) ([]byte, error) {
+	t, err := DeriveTrie(items)
+	if err != nil {
+		return nil, err
+	}
+	return t.Hash(), nil
+}
+
+// ProveItem returns the merkle proof for the item at index i in the trie of the list of items.
+func ProveItem(items [][]byte, i int) (proof.Proof, error) {
+	if i < 0 || i >= len(items) {
+		return nil, fmt.Errorf("index %v is out of range of %v items", i, len(items))
+	}
+
+	t, err := DeriveTrie(items)
+	if err != nil {
+		return nil, err
+	}
+	p, _, err := t.Prove(IndexKey(i))
+	return p, err
+}
+
+// VerifyItemProof verifies the proof of the item at index i under the root hash of
+// a list trie, and returns the RLP encoded item.
+// ErrItemNotIncluded is returned if the proof shows there is no item at the index.
+func VerifyItemProof(rootHash []byte, i int, p proof.Proof) ([]byte, error) {
+	item, found, err := proof.VerifyProof(rootHash, IndexKey(i), p)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: index %v", ErrItemNotIncluded, i)
+	}
+	return item, nil
+}
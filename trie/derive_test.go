@@ -0,0 +1,113 @@
+package trie
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/stretchr/testify/require"
+)
+
+// the transactions of block 10593417 on mainnet
+var block10593417 = []string{
+	"f8ab81a5852e90edd00083012bc294a3bed4e1c75d00fa6f4e5e6922db7261b5e9acd280b844a9059cbb0000000000000000000000008bda8b9823b8490e8cf220dc7b91d97da1c54e250000000000000000000000000000000000000000000000056bc75e2d6310000026a06c89b57113cf7da8aed7911310e03d49be5e40de0bd73af4c9c54726c478691ba056223f039fab98d47c71f84190cf285ce8fc7d9181d6769387e5efd0a970e2e9",
+	"f8ab81a6852e90edd00083012bc294a3bed4e1c75d00fa6f4e5e6922db7261b5e9acd280b844a9059cbb0000000000000000000000008bda8b9823b8490e8cf220dc7b91d97da1c54e250000000000000000000000000000000000000000000000056bc75e2d6310000026a0d77c66153a661ecc986611dffda129e14528435ed3fd244c3afb0d434e9fd1c1a05ab202908bf6cbc9f57c595e6ef3229bce80a15cdf67487873e57cc7f5ad7c8a",
+	"f86d8229f185199c82cc008252089488e9a2d38e66057e18545ce03b3ae9ce4fc360538702ce7de1537c008025a096e7a1d9683b205f697b4073a3e2f0d0ad42e708f03e899c61ed6a894a7f916aa05da238fbb96d41a4b5ec0338c86cfcb627d0aa8e556f21528e62f31c32f7e672",
+	"f86f826b2585199c82cc0083015f9094e955ede0a3dbf651e2891356ecd0509c1edb8d9c8801051fdc4efdc0008025a02190f26e70a82d7f66354a13cda79b6af1aa808db768a787aeb348d425d7d0b3a06a82bd0518bc9b69dc551e20d772a1b06222edfc5d39b6973e4f4dc46ed8b196",
+}
+
+func TestDeriveSha(t *testing.T) {
+	t.Run("should match the transactionsRoot of block 10593417", func(t *testing.T) {
+		items := make([][]byte, len(block10593417))
+		for i, tx := range block10593417 {
+			item, err := hex.DecodeString(tx)
+			require.NoError(t, err)
+			items[i] = item
+		}
+
+		transactionsRoot := common.HexToHash("0xab41f886be23cd786d8a69a72b0f988ea72e0b2e03970d0798f5e03763a442cc")
+		root, err := DeriveSha(items)
+		require.NoError(t, err)
+		require.Equal(t, transactionsRoot[:], root)
+	})
+
+	t.Run("should be the empty root for no items", func(t *testing.T) {
+		root, err := DeriveSha(nil)
+		require.NoError(t, err)
+		require.Equal(t, node.EmptyNodeHash, root)
+	})
+
+	t.Run("should match DeriveSha of ethereum", func(t *testing.T) {
+		// more than 128 items, so that the keys of the indexes are of different lengths
+		for _, n := range []int{1, 2, 127, 128, 129, 300} {
+			txs := make(types.Transactions, n)
+			items := make([][]byte, n)
+			for i := range txs {
+				txs[i] = types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(int64(i)), 21000, big.NewInt(1), nil)
+				items[i] = types.Transactions{txs[i]}.GetRlp(0)
+			}
+
+			expected := types.DeriveSha(txs)
+			root, err := DeriveSha(items)
+			require.NoError(t, err)
+			require.Equal(t, expected[:], root, "%v items", n)
+		}
+	})
+}
+
+func TestProveItem(t *testing.T) {
+	items := make([][]byte, 200)
+	for i := range items {
+		items[i] = []byte{byte(i), 0xff}
+	}
+	rootHash, err := DeriveSha(items)
+	require.NoError(t, err)
+
+	t.Run("should prove every item", func(t *testing.T) {
+		for i := range items {
+			p, err := ProveItem(items, i)
+			require.NoError(t, err)
+
+			item, err := VerifyItemProof(rootHash, i, p)
+			require.NoError(t, err)
+			require.Equal(t, items[i], item)
+
+			// also accepted by ethereum
+			item, err = trie.VerifyProof(common.BytesToHash(rootHash), IndexKey(i), p)
+			require.NoError(t, err)
+			require.Equal(t, items[i], item)
+		}
+	})
+
+	t.Run("should fail for an index out of range", func(t *testing.T) {
+		_, err := ProveItem(items, len(items))
+		require.Error(t, err)
+
+		_, err = ProveItem(items, -1)
+		require.Error(t, err)
+	})
+
+	t.Run("should fail to verify an item that is not included", func(t *testing.T) {
+		tr, err := DeriveTrie(items)
+		require.NoError(t, err)
+		p, found, err := tr.Prove(IndexKey(len(items)))
+		require.NoError(t, err)
+		require.False(t, found)
+
+		_, err = VerifyItemProof(rootHash, len(items), p)
+		require.True(t, errors.Is(err, ErrItemNotIncluded), err)
+	})
+
+	t.Run("should fail to verify the proof of an item with another index", func(t *testing.T) {
+		p, err := ProveItem(items, 1)
+		require.NoError(t, err)
+
+		_, err = VerifyItemProof(rootHash, 150, p)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
@@ -0,0 +1,150 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// dotValueLen is the number of bytes of a value shown in a DOT graph,
+// longer values are truncated.
+const dotValueLen = 8
+
+// ToDOT writes the structure of the trie as a Graphviz DOT graph, which can be
+// rendered with `dot -Tpng`. Each node shows its type, the first bytes of its hash,
+// and its path and value. The edges to the children which are embedded in their
+// parent, because they are serialized to less than the size of a hash, are dashed.
+// The nodes which are not loaded yet are loaded from the key-value store, or shown
+// as hash nodes if the trie has no key-value store.
+func (t *Trie) ToDOT(w io.Writer) error {
+	d := &dotWriter{trie: t}
+	d.printf("digraph trie {\n")
+	d.printf("\tnode [shape=record, fontname=\"monospace\"];\n")
+	if node.IsEmptyNode(t.root) {
+		d.printf("\tn0 [label=\"{empty|%v}\"];\n", shortHash(node.EmptyRoot(t.hasher)))
+	} else if _, err := d.write(t.root, false); err != nil {
+		return err
+	}
+	d.printf("}\n")
+
+	if _, err := w.Write(d.buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write DOT graph: %w", err)
+	}
+	return nil
+}
+
+type dotWriter struct {
+	trie  *Trie
+	buf   bytes.Buffer
+	nodes int
+}
+
+func (d *dotWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&d.buf, format, args...)
+}
+
+// write writes the node and its descendants, and returns the id of the node.
+func (d *dotWriter) write(n node.Node, inline bool) (string, error) {
+	if hash, ok := n.(node.HashNode); ok && d.trie.db != nil {
+		resolved, err := d.trie.resolve(hash)
+		if err != nil {
+			return "", err
+		}
+		n = resolved
+	}
+
+	id := fmt.Sprintf("n%d", d.nodes)
+	d.nodes++
+
+	// an embedded node is not referenced by its hash, so it's not shown
+	header := "inline"
+	if !inline {
+		header = shortHash(node.HashWith(d.trie.hasher, n))
+	}
+
+	if hash, ok := n.(node.HashNode); ok {
+		d.printf("\t%v [shape=box, style=dashed, label=\"hash %v\"];\n", id, shortHash(hash))
+		return id, nil
+	}
+
+	if leaf, ok := n.(*node.LeafNode); ok {
+		d.printf("\t%v [label=\"{leaf %v|path: %v|value: %v}\"];\n",
+			id, header, leaf.Path, dotValue(leaf.Value))
+		return id, nil
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		slots := make([]string, len(branch.Branches))
+		for i := range branch.Branches {
+			slots[i] = fmt.Sprintf("<p%x>%x", i, i)
+		}
+		value := ""
+		if branch.HasValue() {
+			value = dotValue(branch.Value)
+		}
+		d.printf("\t%v [label=\"{branch %v|{%v}|value: %v}\"];\n",
+			id, header, strings.Join(slots, "|"), value)
+
+		for i, child := range branch.Branches {
+			if node.IsEmptyNode(child) {
+				continue
+			}
+			if err := d.writeChild(fmt.Sprintf("%v:p%x", id, i), child); err != nil {
+				return "", err
+			}
+		}
+		return id, nil
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		d.printf("\t%v [label=\"{extension %v|path: %v}\"];\n", id, header, ext.Path)
+		if err := d.writeChild(id, ext.Next); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+
+	return "", unknownNode(n)
+}
+
+// writeChild writes the child node, and the edge from its parent.
+func (d *dotWriter) writeChild(from string, child node.Node) error {
+	inline := false
+	if _, ok := child.(node.HashNode); !ok {
+		serialized, err := node.SerializeWith(d.trie.hasher, child)
+		if err != nil {
+			return err
+		}
+		inline = len(serialized) < d.trie.hasher.Size()
+	}
+
+	id, err := d.write(child, inline)
+	if err != nil {
+		return err
+	}
+
+	if inline {
+		d.printf("\t%v -> %v [style=dashed];\n", from, id)
+	} else {
+		d.printf("\t%v -> %v;\n", from, id)
+	}
+	return nil
+}
+
+// shortHash returns the first 4 bytes of the hash in hex.
+func shortHash(hash []byte) string {
+	if len(hash) > 4 {
+		hash = hash[:4]
+	}
+	return fmt.Sprintf("%x", hash)
+}
+
+func dotValue(value []byte) string {
+	if len(value) > dotValueLen {
+		return fmt.Sprintf("%x... (%d bytes)", value[:dotValueLen], len(value))
+	}
+	return fmt.Sprintf("%x", value)
+}
@@ -0,0 +1,88 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func toDOT(t *testing.T, tr *Trie) string {
+	var buf bytes.Buffer
+	require.NoError(t, tr.ToDOT(&buf))
+	return buf.String()
+}
+
+func TestToDOT(t *testing.T) {
+	t.Run("should render the nodes of the trie", func(t *testing.T) {
+		tr := NewTrie()
+		require.NoError(t, tr.Put([]byte{1, 2, 3, 4}, []byte("hello")))
+		require.NoError(t, tr.Put([]byte{1, 2, 3, 5}, []byte("a value longer than 32 bytes......")))
+
+		// the leaf of the short value is embedded in the branch node
+		require.Equal(t, `digraph trie {
+	node [shape=record, fontname="monospace"];
+	n0 [label="{extension a2053411|path: 0102030}"];
+	n1 [label="{branch aba5876d|{<p0>0|<p1>1|<p2>2|<p3>3|<p4>4|<p5>5|<p6>6|<p7>7|<p8>8|<p9>9|<pa>a|<pb>b|<pc>c|<pd>d|<pe>e|<pf>f}|value: }"];
+	n2 [label="{leaf inline|path: |value: 68656c6c6f}"];
+	n1:p4 -> n2 [style=dashed];
+	n3 [label="{leaf 0da270a0|path: |value: 612076616c756520... (34 bytes)}"];
+	n1:p5 -> n3;
+	n0 -> n1;
+}
+`, toDOT(t, tr))
+	})
+
+	t.Run("should render an empty trie", func(t *testing.T) {
+		require.Equal(t, `digraph trie {
+	node [shape=record, fontname="monospace"];
+	n0 [label="{empty|56e81f17}"];
+}
+`, toDOT(t, NewTrie()))
+	})
+
+	t.Run("should load the nodes from the key-value store", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(tr, 100)
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		require.Equal(t, toDOT(t, tr), toDOT(t, reopened))
+	})
+
+	t.Run("should render the nodes which can't be loaded as hash nodes", func(t *testing.T) {
+		b := NewBuilder(nil)
+		require.NoError(t, b.Add([]byte("key1"), []byte(strings.Repeat("a", 32))))
+		require.NoError(t, b.Add([]byte("key2"), []byte("value")))
+		tr, err := b.Finish()
+		require.NoError(t, err)
+		require.Contains(t, toDOT(t, tr), "[shape=box, style=dashed, label=\"hash ")
+	})
+
+	t.Run("should fail if a node is missing", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(tr, 100)
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+
+		// the keys share the prefix "key", so the root node is an extension node
+		root, ok := reopened.root.(*node.ExtensionNode)
+		require.True(t, ok)
+		require.NoError(t, db.Delete(root.Next.(node.HashNode)))
+
+		err = reopened.ToDOT(&bytes.Buffer{})
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
@@ -0,0 +1,129 @@
+package trie
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// funcHasher is a hasher which isn't comparable, since it's a func.
+type funcHasher func(data []byte) []byte
+
+func (h funcHasher) Hash(data []byte) []byte {
+	return h(data)
+}
+
+func (funcHasher) Size() int {
+	return 32
+}
+
+func TestWithHasher(t *testing.T) {
+	t.Run("should hash the empty trie with the hasher", func(t *testing.T) {
+		emptySHA256 := sha256.Sum256([]byte{0x80})
+		require.Equal(t, emptySHA256[:], NewTrie(WithHasher(node.SHA256)).Hash())
+		require.Equal(t, node.EmptyNodeHash, NewTrie().Hash())
+	})
+
+	t.Run("should hash the nodes with the hasher", func(t *testing.T) {
+		keccak := NewTrie()
+		sha := NewTrie(WithHasher(node.SHA256))
+		putKeys(keccak, 100)
+		putKeys(sha, 100)
+		require.NotEqual(t, keccak.Hash(), sha.Hash())
+		require.Len(t, sha.Hash(), 32)
+
+		// the same trie built in other ways gets the same root hash
+		parallel := NewTrie(WithHasher(node.SHA256), WithParallelHashing(2))
+		putKeys(parallel, 1000)
+		putKeys(sha, 1000)
+		require.Equal(t, sha.Hash(), parallel.Hash())
+
+		built, err := BuildFromSorted(NewSliceIterator(keyValuesOf(sha)), storage.NewMemoryStore(), WithHasher(node.SHA256))
+		require.NoError(t, err)
+		require.Equal(t, sha.Hash(), built.Hash())
+	})
+
+	t.Run("should memoize the hashes of a hasher which isn't comparable", func(t *testing.T) {
+		h := funcHasher(func(data []byte) []byte { return node.SHA256.Hash(data) })
+		tr := NewTrie(WithHasher(h))
+		sha := NewTrie(WithHasher(node.SHA256))
+		putKeys(tr, 100)
+		putKeys(sha, 100)
+		require.Equal(t, sha.Hash(), tr.Hash())
+		require.Equal(t, 0, unhashedNodes(tr.root, tr.hasher, parallelHashingThreshold))
+	})
+
+	t.Run("should commit and open a trie with the hasher", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db, WithHasher(node.BLAKE2b256))
+		require.NoError(t, err)
+		putKeys(tr, 100)
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+		require.Equal(t, tr.Hash(), hash)
+
+		reopened, err := New(hash, db, WithHasher(node.BLAKE2b256))
+		require.NoError(t, err)
+		value, found, err := reopened.Get([]byte("key42"))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("value42"), value)
+
+		// the nodes don't match their hashes with another hasher
+		_, err = New(hash, db)
+		require.Error(t, err)
+	})
+
+	t.Run("should verify the proofs with the hasher", func(t *testing.T) {
+		tr := NewTrie(WithHasher(node.SHA256))
+		putKeys(tr, 100)
+
+		p, found, err := tr.Prove([]byte("key42"))
+		require.NoError(t, err)
+		require.True(t, found)
+		value, found, err := proof.VerifyProofWithHasher(node.SHA256, tr.Hash(), []byte("key42"), p)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("value42"), value)
+
+		_, _, err = proof.VerifyProof(tr.Hash(), []byte("key42"), p)
+		require.Error(t, err)
+
+		absent, found, err := tr.Prove([]byte("absent"))
+		require.NoError(t, err)
+		require.False(t, found)
+		require.NoError(t, proof.VerifyAbsenceProofWithHasher(node.SHA256, tr.Hash(), []byte("absent"), absent))
+		require.Error(t, proof.VerifyAbsenceProof(tr.Hash(), []byte("absent"), absent))
+		err = proof.VerifyAbsenceProofWithHasher(node.SHA256, tr.Hash(), []byte("key42"), p)
+		require.True(t, errors.Is(err, proof.ErrKeyExists), err)
+
+		encoded, err := proof.EncodeWithHasher(node.SHA256, tr.Hash(), []byte("key42"), p)
+		require.NoError(t, err)
+		key, decoded, err := proof.DecodeWithHasher(node.SHA256, encoded)
+		require.NoError(t, err)
+		value, found, err = proof.VerifyProofWithHasher(node.SHA256, tr.Hash(), key, decoded)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("value42"), value)
+	})
+
+	t.Run("should verify the range proofs with the hasher", func(t *testing.T) {
+		tr := NewTrie(WithHasher(node.SHA256))
+		putKeys(tr, 100)
+		keys := [][]byte{[]byte("key10"), []byte("key11")}
+		values := [][]byte{[]byte("value10"), []byte("value11")}
+
+		p, err := tr.ProveRange(keys[0], keys[1])
+		require.NoError(t, err)
+		require.NoError(t, VerifyRangeProof(tr.Hash(), keys[0], keys, values, p, WithHasher(node.SHA256)))
+		require.Error(t, VerifyRangeProof(tr.Hash(), keys[0], keys, values, p))
+
+		empty := NewTrie(WithHasher(node.SHA256))
+		require.NoError(t, VerifyRangeProof(empty.Hash(), nil, nil, nil, nil, WithHasher(node.SHA256)))
+	})
+}
@@ -0,0 +1,129 @@
+package trie
+
+import (
+	"fmt"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// Iterator iterates over the key-value pairs of a trie in the lexicographic order of the keys.
+type Iterator struct {
+	trie  *Trie
+	start []nibble.Nibble
+	// stack holds the nodes to visit, the top of the stack is the next node in order.
+	stack []iteratorItem
+
+	key   []byte
+	value []byte
+	err   error
+}
+
+type iteratorItem struct {
+	node node.Node
+	// path is the nibbles from the root node to the node
+	path []nibble.Nibble
+}
+
+// NewIterator returns an iterator over the key-value pairs whose keys are
+// greater than or equal to start. A nil start iterates over the whole trie.
+// The trie must not be updated while iterating.
+func (t *Trie) NewIterator(start []byte) *Iterator {
+	it := &Iterator{
+		trie:  t,
+		start: nibble.FromBytes(start),
+	}
+	if !node.IsEmptyNode(t.root) {
+		it.stack = append(it.stack, iteratorItem{node: t.root, path: []nibble.Nibble{}})
+	}
+	return it
+}
+
+// Next moves the iterator to the next key-value pair, and returns false
+// if there is no more pair or an error occurred.
+func (it *Iterator) Next() bool {
+	for len(it.stack) > 0 {
+		item := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		// skip the whole sub trie if all of its keys are before the start key
+		if nibble.Compare(item.path, it.start) < 0 && nibble.PrefixMatchedLen(item.path, it.start) < len(item.path) {
+			continue
+		}
+
+		n := item.node
+		if hash, ok := n.(node.HashNode); ok {
+			resolved, err := it.trie.resolve(hash)
+			if err != nil {
+				it.err = err
+				it.stack = nil
+				return false
+			}
+			n = resolved
+		}
+
+		if leaf, ok := n.(*node.LeafNode); ok {
+			path := concatPath(item.path, leaf.Path)
+			if nibble.Compare(path, it.start) < 0 {
+				continue
+			}
+			return it.yield(path, leaf.Value)
+		}
+
+		if branch, ok := n.(*node.BranchNode); ok {
+			// push in reverse order, so that the smallest nibble is visited first
+			for i := 15; i >= 0; i-- {
+				if node.IsEmptyNode(branch.Branches[i]) {
+					continue
+				}
+				path := concat(item.path, []nibble.Nibble{nibble.Nibble(i)})
+				it.stack = append(it.stack, iteratorItem{node: branch.Branches[i], path: path})
+			}
+
+			// the value of a branch is for the key of its path, which is
+			// smaller than the keys of its children.
+			if branch.HasValue() && nibble.Compare(item.path, it.start) >= 0 {
+				return it.yield(item.path, branch.Value)
+			}
+			continue
+		}
+
+		if ext, ok := n.(*node.ExtensionNode); ok {
+			it.stack = append(it.stack, iteratorItem{node: ext.Next, path: concatPath(item.path, ext.Path)})
+			continue
+		}
+	}
+
+	it.key, it.value = nil, nil
+	return false
+}
+
+// yield sets the current key-value pair, and fails if the path is not the path
+// of a key, which can only be the case in a corrupt trie.
+func (it *Iterator) yield(path []nibble.Nibble, value []byte) bool {
+	key, err := nibble.ToBytes(path)
+	if err != nil {
+		it.err = fmt.Errorf("%w: invalid key path: %v", node.ErrCorruptNode, err)
+		it.stack = nil
+		it.key, it.value = nil, nil
+		return false
+	}
+
+	it.key, it.value = key, value
+	return true
+}
+
+// Key returns the key of the current key-value pair.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current key-value pair.
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+// Err returns the error occurred while iterating, e.g. a node could not be loaded.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,101 @@
+package trie
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, it *Iterator) ([][]byte, [][]byte) {
+	var keys, values [][]byte
+	for it.Next() {
+		keys = append(keys, it.Key())
+		values = append(values, it.Value())
+	}
+	require.NoError(t, it.Err())
+	return keys, values
+}
+
+func TestIterator(t *testing.T) {
+	tr := NewTrie()
+	for _, key := range [][]byte{{1, 2, 3, 4}, {1, 2, 3}, {1, 2, 3, 5}, {1}, {2}, {1, 2, 0xff}, {0x10}} {
+		tr.Put(key, append([]byte("v"), key...))
+	}
+	sorted := [][]byte{{1}, {1, 2, 3}, {1, 2, 3, 4}, {1, 2, 3, 5}, {1, 2, 0xff}, {2}, {0x10}}
+
+	t.Run("should iterate over all keys in order", func(t *testing.T) {
+		keys, values := collect(t, tr.NewIterator(nil))
+		require.Equal(t, sorted, keys)
+		for i, key := range keys {
+			require.Equal(t, append([]byte("v"), key...), values[i])
+		}
+	})
+
+	t.Run("should start at the given key", func(t *testing.T) {
+		keys, _ := collect(t, tr.NewIterator([]byte{1, 2, 3, 4}))
+		require.Equal(t, sorted[2:], keys)
+	})
+
+	t.Run("should start at the next key if the given key doesn't exist", func(t *testing.T) {
+		keys, _ := collect(t, tr.NewIterator([]byte{1, 2}))
+		require.Equal(t, sorted[1:], keys)
+
+		keys, _ = collect(t, tr.NewIterator([]byte{1, 2, 3, 4, 0}))
+		require.Equal(t, sorted[3:], keys)
+
+		keys, _ = collect(t, tr.NewIterator([]byte{3}))
+		require.Equal(t, sorted[6:], keys)
+	})
+
+	t.Run("should iterate over nothing if the start key is after all keys", func(t *testing.T) {
+		keys, _ := collect(t, tr.NewIterator([]byte{0x10, 0}))
+		require.Empty(t, keys)
+	})
+
+	t.Run("should iterate over nothing in an empty trie", func(t *testing.T) {
+		keys, _ := collect(t, NewTrie().NewIterator(nil))
+		require.Empty(t, keys)
+	})
+}
+
+func TestIteratorRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+
+	kv := make(map[string][]byte)
+	for i := 0; i < 1000; i++ {
+		key := make([]byte, 1+rnd.Intn(5))
+		rnd.Read(key)
+		value := make([]byte, 1+rnd.Intn(40))
+		rnd.Read(value)
+		kv[string(key)] = value
+		tr.Put(key, value)
+	}
+
+	sorted := make([][]byte, 0, len(kv))
+	for key := range kv {
+		sorted = append(sorted, []byte(key))
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+
+	// iterate over the committed trie to load the nodes from the store
+	hash, err := tr.Commit()
+	require.NoError(t, err)
+	reopened, err := New(hash, db)
+	require.NoError(t, err)
+
+	for _, start := range [][]byte{nil, sorted[100], {0x80}, {0x80, 0}} {
+		keys, values := collect(t, reopened.NewIterator(start))
+		from := sort.Search(len(sorted), func(i int) bool { return bytes.Compare(sorted[i], start) >= 0 })
+		require.Equal(t, sorted[from:], keys)
+		for i, key := range keys {
+			require.Equal(t, kv[string(key)], values[i])
+		}
+	}
+}
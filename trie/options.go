@@ -0,0 +1,46 @@
+package trie
+
+import (
+	"runtime"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// Option configures a trie when it's created.
+type Option func(*Trie)
+
+// WithParallelHashing hashes the sub tries below the top-level branch node in parallel,
+// with at most the given number of goroutines, or one per CPU if it's not positive.
+// The trie is only hashed in parallel when enough of its nodes have to be hashed, since
+// it's not worth it for a small trie, or for a few updated paths of a large trie whose
+// other nodes are already hashed. The root hash is the same as if it was hashed sequentially.
+func WithParallelHashing(workers int) Option {
+	return func(t *Trie) {
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		t.workers = workers
+	}
+}
+
+// WithHasher hashes the nodes of the trie with the given hasher instead of Keccak256,
+// for tries which don't need to be compatible with Ethereum. The hasher also decides
+// which nodes are embedded in their parent, and the root hash of the empty trie.
+// A trie must be opened with the hasher it was committed with, and its proofs must be
+// verified with it too, see proof.VerifyProofWithHasher.
+// A hasher whose type isn't comparable is given an identity, so that the hashes
+// memoized by the nodes of the trie are reused.
+func WithHasher(h node.Hasher) Option {
+	if !node.SameHasher(h, h) {
+		h = &identifiedHasher{h}
+	}
+	return func(t *Trie) {
+		t.hasher = h
+	}
+}
+
+// identifiedHasher gives an identity to a hasher which can't be compared, since a pointer
+// can be compared.
+type identifiedHasher struct {
+	node.Hasher
+}
@@ -0,0 +1,65 @@
+package trie
+
+import (
+	"sync"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// parallelHashingThreshold is the number of nodes to hash, from which the trie is hashed
+// in parallel. The nodes whose hash is memoized are not hashed again, so they don't count.
+const parallelHashingThreshold = 1000
+
+// unhashedNodes returns the number of nodes whose hash isn't memoized in the sub trie of
+// the node, it stops counting at the limit so that it only visits a few nodes of large tries.
+// The descendants of a node whose hash is memoized have been hashed too, so they are skipped.
+func unhashedNodes(n node.Node, h node.Hasher, limit int) int {
+	if limit <= 0 || node.IsHashed(h, n) {
+		return 0
+	}
+
+	count := 1
+	if branch, ok := n.(*node.BranchNode); ok {
+		for _, child := range branch.Branches {
+			if count >= limit {
+				break
+			}
+			count += unhashedNodes(child, h, limit-count)
+		}
+	}
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		count += unhashedNodes(ext.Next, h, limit-count)
+	}
+	return count
+}
+
+// hashParallel hashes the children of the top-level branch node in parallel, so that
+// their hashes are memoized when the root node is hashed.
+func hashParallel(root node.Node, workers int, h node.Hasher) {
+	// the top-level branch node is below the extension node of the prefix shared by all keys
+	if ext, ok := root.(*node.ExtensionNode); ok {
+		root = ext.Next
+	}
+	branch, ok := root.(*node.BranchNode)
+	if !ok {
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for _, child := range branch.Branches {
+		// a hash node is only a reference, there is nothing to hash below it
+		if _, ok := child.(node.HashNode); ok || node.IsEmptyNode(child) {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(child node.Node) {
+			defer wg.Done()
+			node.HashWith(h, child)
+			<-sem
+		}(child)
+	}
+	wg.Wait()
+}
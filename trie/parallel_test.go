@@ -0,0 +1,131 @@
+package trie
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelHashing(t *testing.T) {
+	t.Run("should get the same root hash as hashing sequentially", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		sequential := NewTrie()
+		parallel := NewTrie(WithParallelHashing(4))
+
+		// rounds of updates below and above the threshold
+		for _, updates := range []int{5000, 10, 100, 1000} {
+			for i := 0; i < updates; i++ {
+				key := make([]byte, 1+rnd.Intn(8))
+				rnd.Read(key)
+				value := []byte(fmt.Sprintf("value%d", rnd.Int()))
+				if rnd.Intn(4) == 0 {
+					sequential.Delete(key)
+					parallel.Delete(key)
+					continue
+				}
+				require.NoError(t, sequential.Put(key, value))
+				require.NoError(t, parallel.Put(key, value))
+			}
+			require.Equal(t, sequential.Hash(), parallel.Hash())
+		}
+	})
+
+	t.Run("should hash a trie whose keys share a prefix", func(t *testing.T) {
+		sequential := NewTrie()
+		parallel := NewTrie(WithParallelHashing(0))
+		putKeys(sequential, 1000)
+		putKeys(parallel, 1000)
+		require.Equal(t, sequential.Hash(), parallel.Hash())
+	})
+
+	t.Run("should count the nodes to hash", func(t *testing.T) {
+		tr := NewTrie()
+		putKeys(tr, 2000)
+		require.Equal(t, parallelHashingThreshold, unhashedNodes(tr.root, tr.hasher, parallelHashingThreshold))
+
+		// only the nodes on the paths of the updated keys have to be hashed again
+		tr.Hash()
+		require.Equal(t, 0, unhashedNodes(tr.root, tr.hasher, parallelHashingThreshold))
+		tr.Put([]byte("key42"), []byte("updated"))
+		updated := unhashedNodes(tr.root, tr.hasher, parallelHashingThreshold)
+		require.True(t, updated > 0 && updated < 10, updated)
+
+		// the memoized hashes are only reused with the same hasher
+		require.Equal(t, parallelHashingThreshold, unhashedNodes(tr.root, node.SHA256, parallelHashingThreshold))
+	})
+
+	t.Run("should hash a snapshot from concurrent readers", func(t *testing.T) {
+		tr := NewTrie(WithParallelHashing(4))
+		putKeys(tr, 5000)
+		snapshot := tr.Snapshot()
+
+		hashes := make([][]byte, 8)
+		var wg sync.WaitGroup
+		for i := range hashes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				hashes[i] = snapshot.Hash()
+			}(i)
+		}
+		wg.Wait()
+
+		sequential := NewTrie()
+		putKeys(sequential, 5000)
+		for _, hash := range hashes {
+			require.Equal(t, sequential.Hash(), hash)
+		}
+	})
+
+	t.Run("should keep hashing in parallel for a copy", func(t *testing.T) {
+		parallel := NewTrie(WithParallelHashing(2))
+		putKeys(parallel, 1000)
+		copied := parallel.Copy()
+		require.Equal(t, 2, copied.workers)
+		require.Equal(t, parallel.Hash(), copied.Hash())
+	})
+}
+
+// hashedKeys returns n keys spread over the trie like the keys of a secure trie
+func hashedKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		var index [8]byte
+		binary.BigEndian.PutUint64(index[:], uint64(i))
+		keys[i] = crypto.Keccak256(index[:])
+	}
+	return keys
+}
+
+// run with -benchtime 3x, building a trie of a million keys is slow
+func BenchmarkHash(b *testing.B) {
+	for _, size := range []int{100000, 1000000} {
+		keys := hashedKeys(size)
+		for _, c := range []struct {
+			name string
+			opts []Option
+		}{
+			{"sequential", nil},
+			{"parallel", []Option{WithParallelHashing(0)}},
+		} {
+			b.Run(fmt.Sprintf("%v/%d", c.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					tr := NewTrie(c.opts...)
+					for _, key := range keys {
+						tr.Put(key, key)
+					}
+					b.StartTimer()
+
+					tr.Hash()
+				}
+			})
+		}
+	}
+}
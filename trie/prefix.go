@@ -0,0 +1,177 @@
+package trie
+
+import (
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+)
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the prefix, in the
+// lexicographic order of the keys. Only the sub trie below the node covering the prefix
+// is visited, not the whole trie. Scanning stops at the first error returned by fn, which
+// is returned as is. The trie must not be updated while scanning.
+// An error wrapping node.ErrMissingNode or node.ErrCorruptNode is returned
+// if a node can't be loaded.
+func (t *Trie) ScanPrefix(prefix []byte, fn func(key, value []byte) error) error {
+	n, path, err := t.findPrefix(nibble.FromBytes(prefix))
+	if err != nil {
+		return err
+	}
+	if node.IsEmptyNode(n) {
+		return nil
+	}
+
+	it := &Iterator{
+		trie:  t,
+		start: path,
+		stack: []iteratorItem{{node: n, path: path}},
+	}
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// findPrefix returns the node whose keys all start with the prefix, and the path from
+// the root node to it, or nil if no key starts with the prefix.
+func (t *Trie) findPrefix(prefix []nibble.Nibble) (node.Node, []nibble.Nibble, error) {
+	n := t.root
+	path := []nibble.Nibble{}
+	remaining := prefix
+	for {
+		if hash, ok := n.(node.HashNode); ok {
+			resolved, err := t.resolve(hash)
+			if err != nil {
+				return nil, nil, err
+			}
+			n = resolved
+			continue
+		}
+
+		if node.IsEmptyNode(n) || len(remaining) == 0 {
+			return n, path, nil
+		}
+
+		if leaf, ok := n.(*node.LeafNode); ok {
+			// the key of the leaf starts with the prefix
+			if leaf.Path.PrefixMatchedLen(remaining) == len(remaining) {
+				return leaf, path, nil
+			}
+			return nil, nil, nil
+		}
+
+		if branch, ok := n.(*node.BranchNode); ok {
+			b := remaining[0]
+			path = concat(path, []nibble.Nibble{b})
+			remaining = remaining[1:]
+			n = branch.Branches[b]
+			continue
+		}
+
+		if ext, ok := n.(*node.ExtensionNode); ok {
+			matched := ext.Path.PrefixMatchedLen(remaining)
+			// the prefix ends within the path of the extension node
+			if matched == len(remaining) {
+				return ext, path, nil
+			}
+			if matched < ext.Path.Len() {
+				return nil, nil, nil
+			}
+			path = concatPath(path, ext.Path)
+			remaining = remaining[matched:]
+			n = ext.Next
+			continue
+		}
+
+		return nil, nil, unknownNode(n)
+	}
+}
+
+// DeletePrefix removes all the keys starting with the prefix, and returns whether any
+// key was removed. The sub trie below the node covering the prefix is cut off at once,
+// without loading its nodes, and the nodes above it are collapsed like in Delete, so that
+// the root hash is the same as if the keys were deleted one by one.
+// An error wrapping node.ErrMissingNode or node.ErrCorruptNode is returned
+// if a node on the path of the prefix can't be loaded.
+func (t *Trie) DeletePrefix(prefix []byte) (bool, error) {
+	root, removed, err := t.removePrefix(t.root, nibble.FromBytes(prefix))
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		t.root = root
+	}
+	return removed, nil
+}
+
+// removePrefix deletes the sub trie of the keys starting with the remaining nibbles
+// from the given node, and returns the node that should replace it. Like remove,
+// it copies the nodes on the path before updating them.
+func (t *Trie) removePrefix(n node.Node, nibbles []nibble.Nibble) (node.Node, bool, error) {
+	if node.IsEmptyNode(n) {
+		return nil, false, nil
+	}
+
+	// all the keys below the node start with the prefix, even if it's not loaded
+	if len(nibbles) == 0 {
+		return nil, true, nil
+	}
+
+	if hash, ok := n.(node.HashNode); ok {
+		resolved, err := t.resolve(hash)
+		if err != nil {
+			return nil, false, err
+		}
+
+		updated, removed, err := t.removePrefix(resolved, nibbles)
+		if err != nil || !removed {
+			// keep the reference, since the node was not changed
+			return hash, false, err
+		}
+		return updated, true, nil
+	}
+
+	if leaf, ok := n.(*node.LeafNode); ok {
+		if leaf.Path.PrefixMatchedLen(nibbles) < len(nibbles) {
+			return leaf, false, nil
+		}
+		return nil, true, nil
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		b, remaining := nibbles[0], nibbles[1:]
+		child, removed, err := t.removePrefix(branch.Branches[b], remaining)
+		if err != nil || !removed {
+			return branch, false, err
+		}
+
+		branch = branch.Copy()
+		if node.IsEmptyNode(child) {
+			branch.RemoveBranch(b)
+		} else {
+			branch.SetBranch(b, child)
+		}
+		collapsed, err := t.collapseBranch(branch)
+		return collapsed, err == nil, err
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		matched := ext.Path.PrefixMatchedLen(nibbles)
+		// the prefix ends within the path of the extension node
+		if matched == len(nibbles) {
+			return nil, true, nil
+		}
+		if matched < ext.Path.Len() {
+			return ext, false, nil
+		}
+
+		next, removed, err := t.removePrefix(ext.Next, nibbles[matched:])
+		if err != nil || !removed {
+			return ext, false, err
+		}
+		return collapseExtension(ext.Path, next), true, nil
+	}
+
+	return nil, false, unknownNode(n)
+}
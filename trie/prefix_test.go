@@ -0,0 +1,181 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// namespacedKeys returns keys like account/<addr>/<field>, some accounts sharing
+// the first bytes of their address, so that the trie has extension nodes.
+func namespacedKeys(rnd *rand.Rand, n int) [][]byte {
+	keys := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		addr := []byte{byte(rnd.Intn(4)), byte(rnd.Intn(4)), byte(rnd.Intn(256))}
+		fields := []string{"balance", "nonce", "code", "storage/0", "storage/1"}
+		key := fmt.Sprintf("account/%x/%v", addr, fields[rnd.Intn(len(fields))])
+		keys = append(keys, []byte(key))
+	}
+	return append(keys, []byte("account"), []byte("account/"), []byte("config"))
+}
+
+func scanPrefix(t *testing.T, tr *Trie, prefix []byte) [][]byte {
+	var keys [][]byte
+	err := tr.ScanPrefix(prefix, func(key, value []byte) error {
+		require.Equal(t, key, value)
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+// keysWithPrefix returns the keys of the trie starting with the prefix, by iterating over all of them.
+func keysWithPrefix(t *testing.T, tr *Trie, prefix []byte) [][]byte {
+	var keys [][]byte
+	all, _ := collect(t, tr.NewIterator(nil))
+	for _, key := range all {
+		if bytes.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// prefixesOf returns prefixes of the keys, and prefixes of no key.
+func prefixesOf(rnd *rand.Rand, keys [][]byte) [][]byte {
+	prefixes := [][]byte{nil, []byte("a"), []byte("account/0"), []byte("accounts"), []byte("config/"), []byte("zzz")}
+	for i := 0; i < 100; i++ {
+		key := keys[rnd.Intn(len(keys))]
+		prefixes = append(prefixes, key[:rnd.Intn(len(key)+1)])
+	}
+	return prefixes
+}
+
+func TestScanPrefix(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	keys := namespacedKeys(rnd, 500)
+	tr := NewTrie()
+	for _, key := range keys {
+		require.NoError(t, tr.Put(key, key))
+	}
+
+	t.Run("should visit the keys starting with the prefix in order", func(t *testing.T) {
+		for _, prefix := range prefixesOf(rnd, keys) {
+			require.Equal(t, keysWithPrefix(t, tr, prefix), scanPrefix(t, tr, prefix), "%q", prefix)
+		}
+		require.Equal(t, [][]byte{[]byte("config")}, scanPrefix(t, tr, []byte("config")))
+		require.Empty(t, scanPrefix(t, NewTrie(), nil))
+	})
+
+	t.Run("should stop at the first error", func(t *testing.T) {
+		stop := errors.New("stop")
+		visited := 0
+		err := tr.ScanPrefix([]byte("account/"), func(key, value []byte) error {
+			visited++
+			if visited == 3 {
+				return stop
+			}
+			return nil
+		})
+		require.Equal(t, stop, err)
+		require.Equal(t, 3, visited)
+	})
+
+	t.Run("should only load the sub trie of the prefix", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		committed, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(committed, 500)
+		hash, err := committed.Commit()
+		require.NoError(t, err)
+
+		// the keys share the prefix "key", the root node is an extension node
+		// whose next node is missing from the store
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		next := reopened.root.(*node.ExtensionNode).Next.(node.HashNode)
+		require.NoError(t, db.Delete(next))
+
+		require.Empty(t, scanPrefix(t, reopened, []byte("value")))
+		err = reopened.ScanPrefix([]byte("key42"), func(key, value []byte) error { return nil })
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
+
+func TestDeletePrefix(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	keys := namespacedKeys(rnd, 500)
+	tr := NewTrie()
+	for _, key := range keys {
+		require.NoError(t, tr.Put(key, key))
+	}
+
+	t.Run("should get the same root hash as deleting the keys one by one", func(t *testing.T) {
+		for _, prefix := range prefixesOf(rnd, keys) {
+			deleted := tr.Copy()
+			removed, err := deleted.DeletePrefix(prefix)
+			require.NoError(t, err)
+
+			expected := tr.Copy()
+			matching := keysWithPrefix(t, tr, prefix)
+			for _, key := range matching {
+				_, err := expected.Delete(key)
+				require.NoError(t, err)
+			}
+			require.Equal(t, len(matching) > 0, removed, "%q", prefix)
+			require.Equal(t, expected.Hash(), deleted.Hash(), "%q", prefix)
+			require.Empty(t, scanPrefix(t, deleted, prefix))
+		}
+
+		// the trie the copies were made from is not affected
+		require.Len(t, scanPrefix(t, tr, nil), len(keysWithPrefix(t, tr, nil)))
+	})
+
+	t.Run("should empty the trie", func(t *testing.T) {
+		deleted := tr.Copy()
+		removed, err := deleted.DeletePrefix(nil)
+		require.NoError(t, err)
+		require.True(t, removed)
+		require.Equal(t, node.EmptyNodeHash, deleted.Hash())
+
+		removed, err = deleted.DeletePrefix(nil)
+		require.NoError(t, err)
+		require.False(t, removed)
+	})
+
+	t.Run("should cut off a sub trie without loading it", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		committed, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(committed, 500)
+		committed.Put([]byte("zebra"), []byte("value"))
+		hash, err := committed.Commit()
+		require.NoError(t, err)
+
+		// the root node is a branch node, the keys sharing the prefix "key" are below
+		// an extension node, whose next node is missing from the store
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		ext, err := reopened.resolve(reopened.root.(*node.BranchNode).Branches[6].(node.HashNode))
+		require.NoError(t, err)
+		require.NoError(t, db.Delete(ext.(*node.ExtensionNode).Next.(node.HashNode)))
+
+		_, err = reopened.DeletePrefix([]byte("key1"))
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+
+		removed, err := reopened.DeletePrefix([]byte("key"))
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		expected := NewTrie()
+		expected.Put([]byte("zebra"), []byte("value"))
+		require.Equal(t, expected.Hash(), reopened.Hash())
+	})
+}
@@ -0,0 +1,239 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mpetrun5/merkle-patricia-trie/nibble"
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+)
+
+// ErrInvalidRangeProof is returned when the key-value pairs are not the complete
+// content of the range under the root hash.
+var ErrInvalidRangeProof = errors.New("invalid range proof")
+
+// ProveRange returns the proofs of both edges of the range from first to last,
+// which allows to verify that a list of key-value pairs is the complete content
+// of the range with VerifyRangeProof. The last key should be the last key in the range
+// that is in the trie, while the first key doesn't need to be in the trie.
+func (t *Trie) ProveRange(first []byte, last []byte) (proof.Proof, error) {
+	proof := proof.NewProofDB()
+	if _, err := t.prove(first, proof); err != nil {
+		return nil, err
+	}
+	if _, err := t.prove(last, proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// VerifyRangeProof verifies that the key-value pairs are all the key-value pairs
+// in the trie with the root hash, whose keys are from first to the last of the keys.
+// The keys must be sorted, and the proof must contain the proofs of the first key
+// and of the last of the keys, as returned by ProveRange.
+//
+// If there are no keys, the proof must show there is no key from first on.
+// If the proof is nil, the key-value pairs must be the whole trie.
+// The options configure the trie rebuilt from the key-value pairs, such as WithHasher
+// for a range of a trie which is not hashed with Keccak256.
+func VerifyRangeProof(rootHash []byte, first []byte, keys [][]byte, values [][]byte, p proof.Proof, opts ...Option) error {
+	if len(keys) != len(values) {
+		return fmt.Errorf("%w: %v keys but %v values", ErrInvalidRangeProof, len(keys), len(values))
+	}
+	for i, key := range keys {
+		if i > 0 && bytes.Compare(keys[i-1], key) >= 0 {
+			return fmt.Errorf("%w: keys are not sorted", ErrInvalidRangeProof)
+		}
+		if len(values[i]) == 0 {
+			return fmt.Errorf("%w: empty value for key %x", ErrInvalidRangeProof, key)
+		}
+	}
+	if len(keys) > 0 && bytes.Compare(keys[0], first) < 0 {
+		return fmt.Errorf("%w: key %x is before the first key %x", ErrInvalidRangeProof, keys[0], first)
+	}
+
+	t := NewTrie(opts...)
+	if p != nil {
+		bounds := rangeBounds{first: nibble.FromBytes(first)}
+		if len(keys) > 0 {
+			bounds.last = nibble.FromBytes(keys[len(keys)-1])
+		}
+
+		// load the nodes on the paths of both edges, and remove the key-value pairs
+		// in between, which are the ones that are going to be put back.
+		var root node.Node
+		if !bytes.Equal(rootHash, node.EmptyRoot(t.hasher)) {
+			root = node.HashNode(rootHash)
+		}
+		root, err := resolvePath(t.hasher, root, bounds.first, p)
+		if err != nil {
+			return err
+		}
+		if bounds.last != nil {
+			root, err = resolvePath(t.hasher, root, bounds.last, p)
+			if err != nil {
+				return err
+			}
+		}
+
+		root, err = bounds.prune(root, []nibble.Nibble{})
+		if err != nil {
+			return err
+		}
+		t.root = root
+	}
+
+	for i, key := range keys {
+		if err := t.Put(key, values[i]); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRangeProof, err)
+		}
+	}
+
+	if !bytes.Equal(t.Hash(), rootHash) {
+		return fmt.Errorf("%w: root hash mismatch, expected %x, got %x", ErrInvalidRangeProof, rootHash, t.Hash())
+	}
+	return nil
+}
+
+// resolvePath replaces the hash nodes on the path of the nibbles with the
+// nodes loaded from the proof, as far as the proof contains them.
+func resolvePath(h node.Hasher, n node.Node, nibbles []nibble.Nibble, p proof.Proof) (node.Node, error) {
+	if hash, ok := n.(node.HashNode); ok {
+		serialized, err := p.Get(hash)
+		if err != nil {
+			// it's only an error if the missing node is needed to verify the range
+			return hash, nil
+		}
+		n, err = node.DecodeWith(h, hash, serialized)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok && len(nibbles) > 0 {
+		b, remaining := nibbles[0], nibbles[1:]
+		child, err := resolvePath(h, branch.Branches[b], remaining, p)
+		if err != nil {
+			return nil, err
+		}
+		branch.SetBranch(b, child)
+		return branch, nil
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		matched := ext.Path.PrefixMatchedLen(nibbles)
+		if matched < ext.Path.Len() {
+			return ext, nil
+		}
+		next, err := resolvePath(h, ext.Next, nibbles[matched:], p)
+		if err != nil {
+			return nil, err
+		}
+		ext.SetNext(next)
+		return ext, nil
+	}
+
+	return n, nil
+}
+
+// rangeBounds are the paths of the first and the last key of a range,
+// the range is unbounded if last is nil.
+type rangeBounds struct {
+	first []nibble.Nibble
+	last  []nibble.Nibble
+}
+
+// contains returns whether the key with the given path is in the range.
+func (r rangeBounds) contains(path []nibble.Nibble) bool {
+	return nibble.Compare(path, r.first) >= 0 && (r.last == nil || nibble.Compare(path, r.last) <= 0)
+}
+
+// covers returns whether all keys prefixed with the path are in the range.
+func (r rangeBounds) covers(path []nibble.Nibble) bool {
+	return nibble.Compare(path, r.first) >= 0 &&
+		(r.last == nil || nibble.Compare(path, r.last) < 0 && !isPrefix(path, r.last))
+}
+
+// excludes returns whether all keys prefixed with the path are out of the range.
+func (r rangeBounds) excludes(path []nibble.Nibble) bool {
+	before := nibble.Compare(path, r.first) < 0 && !isPrefix(path, r.first)
+	after := r.last != nil && nibble.Compare(path, r.last) > 0
+	return before || after
+}
+
+// prune removes the key-value pairs in the range from the node at the given path.
+// The sub tries which are partially in the range must have been loaded, otherwise
+// the proof is incomplete.
+func (r rangeBounds) prune(n node.Node, path []nibble.Nibble) (node.Node, error) {
+	if node.IsEmptyNode(n) || r.excludes(path) {
+		return n, nil
+	}
+
+	if r.covers(path) {
+		return nil, nil
+	}
+
+	if hash, ok := n.(node.HashNode); ok {
+		return nil, fmt.Errorf("%w: proof is incomplete, missing node %x", ErrInvalidRangeProof, []byte(hash))
+	}
+
+	if leaf, ok := n.(*node.LeafNode); ok {
+		if r.contains(concatPath(path, leaf.Path)) {
+			return nil, nil
+		}
+		return leaf, nil
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		empty := true
+		for i, child := range branch.Branches {
+			if node.IsEmptyNode(child) {
+				continue
+			}
+
+			b := nibble.Nibble(i)
+			pruned, err := r.prune(child, concat(path, []nibble.Nibble{b}))
+			if err != nil {
+				return nil, err
+			}
+
+			if node.IsEmptyNode(pruned) {
+				branch.RemoveBranch(b)
+			} else {
+				// set the branch even if it's the same node, since it might have been updated
+				branch.SetBranch(b, pruned)
+				empty = false
+			}
+		}
+
+		if branch.HasValue() && r.contains(path) {
+			branch.RemoveValue()
+		}
+
+		if empty && !branch.HasValue() {
+			return nil, nil
+		}
+		return branch, nil
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		next, err := r.prune(ext.Next, concatPath(path, ext.Path))
+		if err != nil {
+			return nil, err
+		}
+		if node.IsEmptyNode(next) {
+			return nil, nil
+		}
+		ext.SetNext(next)
+		return ext, nil
+	}
+
+	return nil, unknownNode(n)
+}
+
+// isPrefix returns whether prefix is a prefix of path.
+func isPrefix(prefix []nibble.Nibble, path []nibble.Nibble) bool {
+	return nibble.PrefixMatchedLen(prefix, path) == len(prefix)
+}
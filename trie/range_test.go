@@ -0,0 +1,160 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/stretchr/testify/require"
+)
+
+// randomTrie returns a trie with random key-value pairs, and its keys in order
+func randomTrie(rnd *rand.Rand, n int) (*Trie, [][]byte, map[string][]byte) {
+	tr := NewTrie()
+	kv := make(map[string][]byte)
+	for i := 0; i < n; i++ {
+		key := make([]byte, 1+rnd.Intn(4))
+		rnd.Read(key)
+		value := make([]byte, 1+rnd.Intn(40))
+		rnd.Read(value)
+		kv[string(key)] = value
+		tr.Put(key, value)
+	}
+
+	keys := make([][]byte, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return tr, keys, kv
+}
+
+func valuesOf(kv map[string][]byte, keys [][]byte) [][]byte {
+	values := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, kv[string(key)])
+	}
+	return values
+}
+
+func proveRange(t *testing.T, tr *Trie, first []byte, last []byte) proof.Proof {
+	p, err := tr.ProveRange(first, last)
+	require.NoError(t, err)
+	return p
+}
+
+func requireInvalidRange(t *testing.T, err error) {
+	require.True(t, errors.Is(err, ErrInvalidRangeProof), err)
+}
+
+func TestVerifyRangeProof(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr, keys, kv := randomTrie(rnd, 500)
+	rootHash := tr.Hash()
+
+	t.Run("should verify ranges of existing keys", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			start := rnd.Intn(len(keys))
+			end := start + rnd.Intn(len(keys)-start)
+			rangeKeys := keys[start : end+1]
+
+			proof, err := tr.ProveRange(keys[start], keys[end])
+			require.NoError(t, err)
+			err = VerifyRangeProof(rootHash, keys[start], rangeKeys, valuesOf(kv, rangeKeys), proof)
+			require.NoError(t, err, "range %x - %x", keys[start], keys[end])
+		}
+	})
+
+	t.Run("should verify the ranges at both ends of the trie", func(t *testing.T) {
+		first, last := keys[:10], keys[len(keys)-10:]
+		require.NoError(t, VerifyRangeProof(rootHash, first[0], first, valuesOf(kv, first), proveRange(t, tr, first[0], first[9])))
+		require.NoError(t, VerifyRangeProof(rootHash, last[0], last, valuesOf(kv, last), proveRange(t, tr, last[0], last[9])))
+		require.NoError(t, VerifyRangeProof(rootHash, nil, keys, valuesOf(kv, keys), proveRange(t, tr, nil, keys[len(keys)-1])))
+	})
+
+	t.Run("should verify a range starting with a key that doesn't exist", func(t *testing.T) {
+		first := append(append([]byte{}, keys[100]...), 0)
+		require.NotEqual(t, first, keys[101])
+		rangeKeys := keys[101:120]
+
+		proof, err := tr.ProveRange(first, keys[119])
+		require.NoError(t, err)
+		require.NoError(t, VerifyRangeProof(rootHash, first, rangeKeys, valuesOf(kv, rangeKeys), proof))
+	})
+
+	t.Run("should verify the whole trie without proof", func(t *testing.T) {
+		require.NoError(t, VerifyRangeProof(rootHash, nil, keys, valuesOf(kv, keys), nil))
+		requireInvalidRange(t, VerifyRangeProof(rootHash, nil, keys[1:], valuesOf(kv, keys[1:]), nil))
+	})
+
+	t.Run("should verify there are no keys after the first key", func(t *testing.T) {
+		first := append(append([]byte{}, keys[len(keys)-1]...), 0)
+		proof, _, err := tr.Prove(first)
+		require.NoError(t, err)
+		require.NoError(t, VerifyRangeProof(rootHash, first, nil, nil, proof))
+
+		proof, _, err = tr.Prove(keys[100])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], nil, nil, proof))
+	})
+
+	t.Run("should fail if a key is missing", func(t *testing.T) {
+		rangeKeys := append(append([][]byte{}, keys[100:110]...), keys[111:120]...)
+		proof, err := tr.ProveRange(keys[100], keys[119])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, valuesOf(kv, rangeKeys), proof))
+	})
+
+	t.Run("should fail if the first key is missing", func(t *testing.T) {
+		rangeKeys := keys[101:120]
+		proof, err := tr.ProveRange(keys[100], keys[119])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, valuesOf(kv, rangeKeys), proof))
+	})
+
+	t.Run("should fail if a value was changed", func(t *testing.T) {
+		rangeKeys := keys[100:120]
+		values := valuesOf(kv, rangeKeys)
+		values[5] = []byte("changed")
+		proof, err := tr.ProveRange(keys[100], keys[119])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, values, proof))
+	})
+
+	t.Run("should fail if a key was added", func(t *testing.T) {
+		added := append(append([]byte{}, keys[105]...), 0)
+		require.NotEqual(t, added, keys[106])
+		rangeKeys := append(append(append([][]byte{}, keys[100:106]...), added), keys[106:120]...)
+		values := valuesOf(kv, rangeKeys)
+		values[6] = []byte("added")
+		proof, err := tr.ProveRange(keys[100], keys[119])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, values, proof))
+	})
+
+	t.Run("should fail if the keys are not sorted", func(t *testing.T) {
+		rangeKeys := [][]byte{keys[101], keys[100]}
+		proof, err := tr.ProveRange(keys[100], keys[101])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, valuesOf(kv, rangeKeys), proof))
+	})
+
+	t.Run("should fail if the proof of an edge is missing", func(t *testing.T) {
+		rangeKeys := keys[100:120]
+		proof, _, err := tr.Prove(keys[100])
+		require.NoError(t, err)
+		requireInvalidRange(t, VerifyRangeProof(rootHash, keys[100], rangeKeys, valuesOf(kv, rangeKeys), proof))
+	})
+}
+
+func TestVerifyRangeProofEmptyTrie(t *testing.T) {
+	tr := NewTrie()
+	proof, _, err := tr.Prove([]byte{1})
+	require.NoError(t, err)
+	require.NoError(t, VerifyRangeProof(node.EmptyNodeHash, []byte{1}, nil, nil, proof))
+	require.NoError(t, VerifyRangeProof(node.EmptyNodeHash, nil, nil, nil, nil))
+}
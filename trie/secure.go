@@ -0,0 +1,119 @@
+package trie
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+)
+
+// SecureTrie wraps a trie, and keys its entries by the keccak256 hash of the keys,
+// as Ethereum's state and storage tries do. Hashing the keys keeps the trie balanced,
+// since the paths can't be chosen to make it deep.
+type SecureTrie struct {
+	trie *Trie
+	// preimages maps the hashed keys to the original keys, it's nil if the
+	// original keys are not kept.
+	preimages storage.KeyValueStore
+	// pending holds the preimages which are not written to the store yet.
+	pending map[string][]byte
+}
+
+// NewSecureTrie wraps the given trie. If preimages is not nil, the original keys are
+// written to it on Commit, so that they can be looked up by GetKey.
+func NewSecureTrie(trie *Trie, preimages storage.KeyValueStore) *SecureTrie {
+	return &SecureTrie{
+		trie:      trie,
+		preimages: preimages,
+		pending:   make(map[string][]byte),
+	}
+}
+
+// Copy returns a copy of the secure trie sharing its nodes, see Trie.Copy.
+func (s *SecureTrie) Copy() *SecureTrie {
+	pending := make(map[string][]byte, len(s.pending))
+	for hashed, key := range s.pending {
+		pending[hashed] = key
+	}
+	return &SecureTrie{
+		trie:      s.trie.Copy(),
+		preimages: s.preimages,
+		pending:   pending,
+	}
+}
+
+func (s *SecureTrie) Hash() []byte {
+	return s.trie.Hash()
+}
+
+func (s *SecureTrie) Get(key []byte) ([]byte, bool, error) {
+	return s.trie.Get(crypto.Keccak256(key))
+}
+
+func (s *SecureTrie) Put(key []byte, value []byte) error {
+	hashed := crypto.Keccak256(key)
+	if err := s.trie.Put(hashed, value); err != nil {
+		return err
+	}
+	if s.preimages != nil {
+		s.pending[string(hashed)] = append([]byte{}, key...)
+	}
+	return nil
+}
+
+func (s *SecureTrie) Delete(key []byte) (bool, error) {
+	hashed := crypto.Keccak256(key)
+	removed, err := s.trie.Delete(hashed)
+	if err != nil {
+		return false, err
+	}
+	delete(s.pending, string(hashed))
+	return removed, nil
+}
+
+// Prove returns the merkle proof for the given key, the proof is for the
+// hashed key, which is what it has to be verified with.
+func (s *SecureTrie) Prove(key []byte) (proof.Proof, bool, error) {
+	return s.trie.Prove(crypto.Keccak256(key))
+}
+
+// NewIterator returns an iterator over the key-value pairs ordered by the hashed keys,
+// starting at the given hashed key. The iterator returns the hashed keys, GetKey
+// returns the original keys for them.
+func (s *SecureTrie) NewIterator(start []byte) *Iterator {
+	return s.trie.NewIterator(start)
+}
+
+// GetKey returns the original key for the hashed key, or nil if the preimage is unknown.
+func (s *SecureTrie) GetKey(hashed []byte) []byte {
+	if key, ok := s.pending[string(hashed)]; ok {
+		return key
+	}
+	if s.preimages == nil {
+		return nil
+	}
+	key, err := s.preimages.Get(hashed)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// Commit writes the preimages of the keys and commits the trie.
+func (s *SecureTrie) Commit() ([]byte, error) {
+	if len(s.pending) > 0 {
+		batch := s.preimages.NewBatch()
+		for hashed, key := range s.pending {
+			if err := batch.Put([]byte(hashed), key); err != nil {
+				return nil, fmt.Errorf("could not write preimage: %w", err)
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return nil, fmt.Errorf("could not write preimages: %w", err)
+		}
+		s.pending = make(map[string][]byte)
+	}
+
+	return s.trie.Commit()
+}
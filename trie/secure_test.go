@@ -0,0 +1,93 @@
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureTrieMatchesEthSecureTrie(t *testing.T) {
+	st := NewSecureTrie(NewTrie(), nil)
+	mpt, err := trie.NewSecure(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		key, value := []byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i))
+		st.Put(key, value)
+		mpt.Update(key, value)
+	}
+	for i := 0; i < 200; i += 3 {
+		key := []byte(fmt.Sprintf("key%d", i))
+		removed, err := st.Delete(key)
+		require.NoError(t, err)
+		require.True(t, removed)
+		mpt.Delete(key)
+	}
+
+	mptHash := mpt.Hash()
+	require.Equal(t, mptHash[:], st.Hash())
+
+	val, found, err := st.Get([]byte("key1"))
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []byte("value1"), val)
+
+	_, found, err = st.Get([]byte("key0"))
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestSecureTrieProve(t *testing.T) {
+	st := NewSecureTrie(NewTrie(), nil)
+	st.Put([]byte("key"), []byte("value"))
+	st.Put([]byte("other key"), []byte("other value"))
+
+	proof, found, err := st.Prove([]byte("key"))
+	require.NoError(t, err)
+	require.True(t, found)
+
+	val, err := VerifyProof(st.Hash(), crypto.Keccak256([]byte("key")), proof)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), val)
+}
+
+func TestSecureTriePreimages(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+
+	st := NewSecureTrie(tr, db)
+	keys := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		keys[key] = true
+		st.Put([]byte(key), []byte("value"))
+	}
+
+	// the preimages are known before they are committed
+	require.Equal(t, []byte("key1"), st.GetKey(crypto.Keccak256([]byte("key1"))))
+
+	hash, err := st.Commit()
+	require.NoError(t, err)
+
+	reopened, err := New(hash, db)
+	require.NoError(t, err)
+	st = NewSecureTrie(reopened, db)
+
+	it := st.NewIterator(nil)
+	for it.Next() {
+		key := st.GetKey(it.Key())
+		require.True(t, keys[string(key)], "unexpected key %q", key)
+		delete(keys, string(key))
+	}
+	require.NoError(t, it.Err())
+	require.Empty(t, keys)
+
+	require.Nil(t, NewSecureTrie(NewTrie(), nil).GetKey(crypto.Keccak256([]byte("key1"))))
+}
@@ -0,0 +1,57 @@
+package trie
+
+import (
+	"github.com/mpetrun5/merkle-patricia-trie/proof"
+)
+
+// Copy returns a copy of the trie in O(1), which shares all the nodes with the trie.
+// Since the nodes are copied on the path of an update, updating either the trie or the copy
+// doesn't affect the other one.
+func (t *Trie) Copy() *Trie {
+	copied := *t
+	return &copied
+}
+
+// Snapshot returns a read-only view of the current version of the trie in O(1),
+// which isn't affected by later updates of the trie.
+func (t *Trie) Snapshot() *Snapshot {
+	return &Snapshot{trie: t.Copy()}
+}
+
+// Snapshot is a read-only version of a trie.
+type Snapshot struct {
+	trie *Trie
+}
+
+func (s *Snapshot) Hash() []byte {
+	return s.trie.Hash()
+}
+
+func (s *Snapshot) Get(key []byte) ([]byte, bool, error) {
+	return s.trie.Get(key)
+}
+
+func (s *Snapshot) Prove(key []byte) (proof.Proof, bool, error) {
+	return s.trie.Prove(key)
+}
+
+func (s *Snapshot) ProveMany(keys [][]byte) (proof.Proof, []bool, error) {
+	return s.trie.ProveMany(keys)
+}
+
+func (s *Snapshot) ProveRange(first []byte, last []byte) (proof.Proof, error) {
+	return s.trie.ProveRange(first, last)
+}
+
+func (s *Snapshot) NewIterator(start []byte) *Iterator {
+	return s.trie.NewIterator(start)
+}
+
+func (s *Snapshot) ScanPrefix(prefix []byte, fn func(key, value []byte) error) error {
+	return s.trie.ScanPrefix(prefix, fn)
+}
+
+// Copy returns a trie starting from the snapshot, which can be updated.
+func (s *Snapshot) Copy() *Trie {
+	return s.trie.Copy()
+}
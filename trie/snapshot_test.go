@@ -0,0 +1,102 @@
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	tr := NewTrie()
+	putKeys(tr, 100)
+	hash := tr.Hash()
+
+	t.Run("should not affect the trie when the copy is updated", func(t *testing.T) {
+		copied := tr.Copy()
+		require.Equal(t, hash, copied.Hash())
+
+		require.NoError(t, copied.Put([]byte("key1"), []byte("updated")))
+		require.NoError(t, copied.Put([]byte("key100"), []byte("value100")))
+		removed, err := copied.Delete([]byte("key2"))
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		require.Equal(t, hash, tr.Hash())
+		val, found, err := tr.Get([]byte("key1"))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("value1"), val)
+
+		expected := NewTrie()
+		putKeys(expected, 101)
+		expected.Put([]byte("key1"), []byte("updated"))
+		expected.Delete([]byte("key2"))
+		require.Equal(t, expected.Hash(), copied.Hash())
+	})
+
+	t.Run("should not affect the copy when the trie is updated", func(t *testing.T) {
+		original := tr.Copy()
+		copied := original.Copy()
+		for i := 0; i < 100; i += 2 {
+			removed, err := original.Delete([]byte(fmt.Sprintf("key%d", i)))
+			require.NoError(t, err)
+			require.True(t, removed)
+		}
+		require.NotEqual(t, hash, original.Hash())
+		require.Equal(t, hash, copied.Hash())
+	})
+
+	t.Run("should commit both the trie and the copy", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		original, err := New(nil, db)
+		require.NoError(t, err)
+		putKeys(original, 100)
+		_, err = original.Commit()
+		require.NoError(t, err)
+
+		copied := original.Copy()
+		require.NoError(t, copied.Put([]byte("key0"), []byte("updated")))
+		require.NoError(t, original.Put([]byte("key1"), []byte("updated")))
+
+		for _, tr := range []*Trie{original, copied} {
+			hash, err := tr.Commit()
+			require.NoError(t, err)
+			reopened, err := New(hash, db)
+			require.NoError(t, err)
+			it := reopened.NewIterator(nil)
+			keys, _ := collect(t, it)
+			require.Len(t, keys, 100)
+		}
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	tr := NewTrie()
+	putKeys(tr, 100)
+	hash := tr.Hash()
+
+	snapshot := tr.Snapshot()
+	require.NoError(t, tr.Put([]byte("key1"), []byte("updated")))
+	require.NotEqual(t, hash, tr.Hash())
+	require.Equal(t, hash, snapshot.Hash())
+
+	val, found, err := snapshot.Get([]byte("key1"))
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, []byte("value1"), val)
+
+	p, found, err := snapshot.Prove([]byte("key1"))
+	require.NoError(t, err)
+	require.True(t, found)
+	val, err = VerifyProof(hash, []byte("key1"), p)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), val)
+
+	// a trie can be forked from the snapshot
+	forked := snapshot.Copy()
+	require.NoError(t, forked.Put([]byte("key2"), []byte("updated")))
+	require.Equal(t, hash, snapshot.Hash())
+	require.NotEqual(t, tr.Hash(), forked.Hash())
+}
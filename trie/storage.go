@@ -0,0 +1,109 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+)
+
+// ErrNoStore is returned when committing a trie which has no key-value store.
+var ErrNoStore = errors.New("trie has no key-value store")
+
+// New opens the trie with the given root hash from the key-value store.
+// The nodes are loaded lazily from the store when they are accessed.
+// An empty root hash opens an empty trie, which can be committed to the store.
+func New(rootHash []byte, db storage.KeyValueStore, opts ...Option) (*Trie, error) {
+	t := NewTrie(opts...)
+	t.db = db
+	if len(rootHash) == 0 || bytes.Equal(rootHash, node.EmptyRoot(t.hasher)) {
+		return t, nil
+	}
+
+	root, err := t.resolve(node.HashNode(rootHash))
+	if err != nil {
+		return nil, fmt.Errorf("could not open trie: %w", err)
+	}
+	t.root = root
+	return t, nil
+}
+
+// Commit writes the nodes which were created or updated since they were loaded or
+// last committed to the key-value store, keyed by their hashes, and returns the root hash.
+func (t *Trie) Commit() ([]byte, error) {
+	if t.db == nil {
+		return nil, ErrNoStore
+	}
+
+	if node.IsEmptyNode(t.root) {
+		return node.EmptyRoot(t.hasher), nil
+	}
+
+	// hash the trie first, in parallel if the trie is configured to
+	t.Hash()
+
+	batch := t.db.NewBatch()
+	// the root node is always stored, so that the trie can be opened by its hash
+	// even if it's serialized to less than the size of a hash.
+	if err := commit(t.root, batch, t.hasher, true); err != nil {
+		return nil, fmt.Errorf("could not commit trie: %w", err)
+	}
+	if err := batch.Write(); err != nil {
+		return nil, fmt.Errorf("could not write batch: %w", err)
+	}
+
+	return node.HashWith(t.hasher, t.root), nil
+}
+
+// commit adds the node and its dirty descendants which are referenced by their hashes
+// to the batch, and marks them as clean.
+// Nodes serialized to less than the size of a hash are embedded in their parents, so
+// they don't need to be stored separately.
+func commit(n node.Node, batch storage.Batch, h node.Hasher, force bool) error {
+	// a clean node and its descendants are already stored, and so is a hash node
+	if !node.IsDirty(n) {
+		return nil
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		for _, child := range branch.Branches {
+			if err := commit(child, batch, h, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		if err := commit(ext.Next, batch, h, false); err != nil {
+			return err
+		}
+	}
+
+	serialized, err := node.SerializeWith(h, n)
+	if err != nil {
+		return err
+	}
+	if len(serialized) >= h.Size() || force {
+		if err := batch.Put(node.HashWith(h, n), serialized); err != nil {
+			return err
+		}
+	}
+
+	node.MarkClean(n)
+	return nil
+}
+
+// resolve loads the node referenced by the hash from the key-value store.
+func (t *Trie) resolve(hash node.HashNode) (node.Node, error) {
+	if t.db == nil {
+		return nil, fmt.Errorf("%w: %x", node.ErrMissingNode, []byte(hash))
+	}
+
+	serialized, err := t.db.Get(hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %x", node.ErrMissingNode, []byte(hash))
+	}
+	return node.DecodeWith(t.hasher, hash, serialized)
+}
@@ -0,0 +1,263 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mpetrun5/merkle-patricia-trie/node"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func putKeys(t *Trie, n int) {
+	for i := 0; i < n; i++ {
+		t.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+}
+
+// invalidNode is a node whose raw form can't be RLP encoded
+type invalidNode struct{}
+
+func (invalidNode) Hash() []byte {
+	return nil
+}
+
+func (invalidNode) Raw() []interface{} {
+	return []interface{}{func() {}}
+}
+
+func TestCommit(t *testing.T) {
+	t.Run("should fail to commit a trie without key-value store", func(t *testing.T) {
+		tr := NewTrie()
+		_, err := tr.Commit()
+		require.True(t, errors.Is(err, ErrNoStore), err)
+	})
+
+	t.Run("should commit an empty trie", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+		require.Equal(t, node.EmptyNodeHash, hash)
+
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		require.Equal(t, node.EmptyNodeHash, reopened.Hash())
+	})
+
+	t.Run("should fail to commit a trie with a node that can't be serialized", func(t *testing.T) {
+		tr, err := New(nil, storage.NewMemoryStore())
+		require.NoError(t, err)
+		branch := node.NewBranchNode()
+		branch.SetBranch(0, invalidNode{})
+		tr.root = branch
+
+		require.Nil(t, tr.Hash())
+		_, err = tr.Commit()
+		require.True(t, errors.Is(err, node.ErrCorruptNode), err)
+	})
+
+	t.Run("should store a root node serialized to less than 32 bytes", func(t *testing.T) {
+		db := storage.NewMemoryStore()
+		tr, err := New(nil, db)
+		require.NoError(t, err)
+		tr.Put([]byte{1}, []byte("a"))
+
+		hash, err := tr.Commit()
+		require.NoError(t, err)
+
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		val, found, err := reopened.Get([]byte{1})
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("a"), val)
+	})
+
+	t.Run("should keep the trie usable after commit", func(t *testing.T) {
+		tr, err := New(nil, storage.NewMemoryStore())
+		require.NoError(t, err)
+		putKeys(tr, 100)
+		hash := tr.Hash()
+
+		committed, err := tr.Commit()
+		require.NoError(t, err)
+		require.Equal(t, hash, committed)
+		require.Equal(t, hash, tr.Hash())
+
+		val, found, err := tr.Get([]byte("key42"))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("value42"), val)
+	})
+}
+
+func TestCommitDirtyNodes(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+	putKeys(tr, 500)
+	_, err = tr.Commit()
+	require.NoError(t, err)
+	stored := db.Len()
+
+	// committing again writes nothing new
+	_, err = tr.Commit()
+	require.NoError(t, err)
+	require.Equal(t, stored, db.Len())
+
+	// only the nodes on the path of the updated key are written
+	tr.Put([]byte("key42"), []byte("updated"))
+	hash, err := tr.Commit()
+	require.NoError(t, err)
+	require.Less(t, db.Len()-stored, 8)
+
+	expected := NewTrie()
+	putKeys(expected, 500)
+	expected.Put([]byte("key42"), []byte("updated"))
+	require.Equal(t, expected.Hash(), hash)
+}
+
+func TestNew(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+	putKeys(tr, 500)
+	hash, err := tr.Commit()
+	require.NoError(t, err)
+
+	t.Run("should fail to open a trie that is not in the store", func(t *testing.T) {
+		_, err := New([]byte("01234567890123456789012345678901"), db)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+
+	t.Run("should get values from the store", func(t *testing.T) {
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		require.Equal(t, hash, reopened.Hash())
+
+		for i := 0; i < 500; i++ {
+			val, found, err := reopened.Get([]byte(fmt.Sprintf("key%d", i)))
+			require.NoError(t, err)
+			require.True(t, found)
+			require.Equal(t, []byte(fmt.Sprintf("value%d", i)), val)
+		}
+		_, found, err := reopened.Get([]byte("key500"))
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("should update a trie loaded from the store", func(t *testing.T) {
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+		require.NoError(t, reopened.Put([]byte("key500"), []byte("value500")))
+		for i := 0; i < 500; i += 2 {
+			removed, err := reopened.Delete([]byte(fmt.Sprintf("key%d", i)))
+			require.NoError(t, err)
+			require.True(t, removed)
+		}
+
+		expected := NewTrie()
+		putKeys(expected, 501)
+		for i := 0; i < 500; i += 2 {
+			removed, err := expected.Delete([]byte(fmt.Sprintf("key%d", i)))
+			require.NoError(t, err)
+			require.True(t, removed)
+		}
+		require.Equal(t, expected.Hash(), reopened.Hash())
+
+		// the previous version is still in the store
+		updated, err := reopened.Commit()
+		require.NoError(t, err)
+		require.Equal(t, expected.Hash(), updated)
+
+		previous, err := New(hash, db)
+		require.NoError(t, err)
+		_, found, err := previous.Get([]byte("key0"))
+		require.NoError(t, err)
+		require.True(t, found)
+	})
+
+	t.Run("should prove a key of a trie loaded from the store", func(t *testing.T) {
+		reopened, err := New(hash, db)
+		require.NoError(t, err)
+
+		proof, found, err := reopened.Prove([]byte("key42"))
+		require.NoError(t, err)
+		require.True(t, found)
+		val, err := VerifyProof(hash, []byte("key42"), proof)
+		require.NoError(t, err)
+		require.Equal(t, []byte("value42"), val)
+	})
+}
+
+func TestMissingNode(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+	putKeys(tr, 500)
+	hash, err := tr.Commit()
+	require.NoError(t, err)
+
+	// remove the node below the root node from the store, the keys share
+	// the prefix "key", so the root node is an extension node
+	reopened, err := New(hash, db)
+	require.NoError(t, err)
+	root, ok := reopened.root.(*node.ExtensionNode)
+	require.True(t, ok)
+	next, ok := root.Next.(node.HashNode)
+	require.True(t, ok)
+	require.NoError(t, db.Delete(next))
+
+	key := []byte("key42")
+	_, _, err = reopened.Get(key)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+
+	_, err = reopened.Delete(key)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+
+	_, _, err = reopened.Prove(key)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+
+	_, err = reopened.ProveRange(key, key)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+
+	it := reopened.NewIterator(nil)
+	require.False(t, it.Next())
+	require.True(t, errors.Is(it.Err(), node.ErrMissingNode), it.Err())
+
+	// a failed update leaves the trie as it was
+	err = reopened.Put(key, []byte("updated"))
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	require.Equal(t, hash, reopened.Hash())
+}
+
+func TestDeleteMissingSibling(t *testing.T) {
+	db := storage.NewMemoryStore()
+	tr, err := New(nil, db)
+	require.NoError(t, err)
+	// the root node is a branch node with two children, both stored by their hashes
+	first, second := []byte{0x10, 1}, []byte{0x20, 2}
+	tr.Put(first, []byte("a value long enough to be stored by its hash"))
+	tr.Put(second, []byte("another value long enough to be stored by its hash"))
+	hash, err := tr.Commit()
+	require.NoError(t, err)
+
+	reopened, err := New(hash, db)
+	require.NoError(t, err)
+	sibling, ok := reopened.root.(*node.BranchNode).Branches[2].(node.HashNode)
+	require.True(t, ok)
+	require.NoError(t, db.Delete(sibling))
+
+	// the branch node can't be collapsed without loading the remaining child,
+	// the failed delete leaves the trie as it was
+	_, err = reopened.Delete(first)
+	require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	require.Equal(t, hash, reopened.Hash())
+	_, found, err := reopened.Get(first)
+	require.NoError(t, err)
+	require.True(t, found)
+}
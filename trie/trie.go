@@ -3,47 +3,79 @@ package trie
 import (
 	"fmt"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/trie"
 	"github.com/mpetrun5/merkle-patricia-trie/nibble"
 	"github.com/mpetrun5/merkle-patricia-trie/node"
 	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
 )
 
 type Trie struct {
 	root node.Node
+	// db is where the nodes referenced by hash are loaded from, it's nil for
+	// a trie which only lives in memory.
+	db storage.KeyValueStore
+
+	// workers is the number of goroutines hashing the trie in parallel,
+	// it's hashed sequentially if it's 0.
+	workers int
+
+	// hasher hashes the nodes, it's node.Keccak256 unless the trie is created WithHasher.
+	hasher node.Hasher
 }
 
-func NewTrie() *Trie {
-	return &Trie{}
+func NewTrie(opts ...Option) *Trie {
+	t := &Trie{hasher: node.Keccak256}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
+// Hash returns the merkle root hash of the trie. It's nil if a node can't be serialized,
+// which can only be the case for a corrupt trie, so a nil hash must not be compared
+// with other hashes. Commit returns an error wrapping node.ErrCorruptNode instead.
 func (t *Trie) Hash() []byte {
 	if node.IsEmptyNode(t.root) {
-		return node.EmptyNodeHash
+		return node.EmptyRoot(t.hasher)
+	}
+
+	if t.workers > 0 && unhashedNodes(t.root, t.hasher, parallelHashingThreshold) >= parallelHashingThreshold {
+		hashParallel(t.root, t.workers, t.hasher)
 	}
-	return t.root.Hash()
+	return node.HashWith(t.hasher, t.root)
 }
 
-func (t *Trie) Get(key []byte) ([]byte, bool) {
+// Get returns the value for the key, and whether the key was found.
+// An error wrapping node.ErrMissingNode or node.ErrCorruptNode is returned
+// if a node on the path can't be loaded.
+func (t *Trie) Get(key []byte) ([]byte, bool, error) {
 	root := t.root
 	nibbles := nibble.FromBytes(key)
 	for {
+		if hash, ok := root.(node.HashNode); ok {
+			resolved, err := t.resolve(hash)
+			if err != nil {
+				return nil, false, err
+			}
+			root = resolved
+			continue
+		}
+
 		if node.IsEmptyNode(root) {
-			return nil, false
+			return nil, false, nil
 		}
 
 		if leaf, ok := root.(*node.LeafNode); ok {
-			matched := nibble.PrefixMatchedLen(leaf.Path, nibbles)
-			if matched != len(leaf.Path) || matched != len(nibbles) {
-				return nil, false
+			matched := leaf.Path.PrefixMatchedLen(nibbles)
+			if matched != leaf.Path.Len() || matched != len(nibbles) {
+				return nil, false, nil
 			}
-			return leaf.Value, true
+			return leaf.Value, true, nil
 		}
 
 		if branch, ok := root.(*node.BranchNode); ok {
 			if len(nibbles) == 0 {
-				return branch.Value, branch.HasValue()
+				return branch.Value, branch.HasValue(), nil
 			}
 
 			b, remaining := nibbles[0], nibbles[1:]
@@ -53,11 +85,11 @@ func (t *Trie) Get(key []byte) ([]byte, bool) {
 		}
 
 		if ext, ok := root.(*node.ExtensionNode); ok {
-			matched := nibble.PrefixMatchedLen(ext.Path, nibbles)
+			matched := ext.Path.PrefixMatchedLen(nibbles)
 			// E 01020304
 			//   010203
-			if matched < len(ext.Path) {
-				return nil, false
+			if matched < ext.Path.Len() {
+				return nil, false, nil
 			}
 
 			nibbles = nibbles[matched:]
@@ -65,7 +97,7 @@ func (t *Trie) Get(key []byte) ([]byte, bool) {
 			continue
 		}
 
-		panic("not found")
+		return nil, false, unknownNode(root)
 	}
 }
 
@@ -74,166 +106,401 @@ func (t *Trie) Get(key []byte) ([]byte, bool) {
 // - When stopped at an EmptyNode, replace it with a new LeafNode with the remaining path.
 // - When stopped at a LeafNode, convert it to an ExtensionNode and add a new branch and a new LeafNode.
 // - When stopped at an ExtensionNode, convert it to another ExtensionNode with shorter path and create a new BranchNode points to the ExtensionNode.
-func (t *Trie) Put(key []byte, value []byte) {
-	// need to use pointer, so that I can update root in place without
-	// keeping trace of the parent node
-	root := &t.root
-	nibbles := nibble.FromBytes(key)
-	for {
-		if node.IsEmptyNode(*root) {
-			leaf := node.NewLeafNodeFromNibbles(nibbles, value)
-			*root = leaf
-			return
+// The trie is not updated if an error is returned because a node on the path can't be loaded.
+func (t *Trie) Put(key []byte, value []byte) error {
+	root, err := t.insert(t.root, nibble.PathFromBytes(key), value)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// insert adds the value for the remaining path of the key under the given node, and returns
+// the node that should replace it. The nodes on the path are copied before they are
+// updated, so that the nodes are never modified once they are in a trie, and can be
+// shared by several versions of it. The nodes which are not on the path are shared.
+// The paths of the new nodes share the bytes of the path of the key.
+func (t *Trie) insert(n node.Node, path nibble.Path, value []byte) (node.Node, error) {
+	// load the node, since it's going to be updated
+	if hash, ok := n.(node.HashNode); ok {
+		resolved, err := t.resolve(hash)
+		if err != nil {
+			return nil, err
+		}
+		n = resolved
+	}
+
+	if node.IsEmptyNode(n) {
+		return node.NewLeafNodeFromPath(path, value), nil
+	}
+
+	if leaf, ok := n.(*node.LeafNode); ok {
+		matched := leaf.Path.MatchedLen(path)
+
+		// if all matched, update value even if the value are equal
+		if matched == path.Len() && matched == leaf.Path.Len() {
+			return node.NewLeafNodeFromPath(leaf.Path, value), nil
 		}
 
-		if leaf, ok := (*root).(*node.LeafNode); ok {
-			matched := nibble.PrefixMatchedLen(leaf.Path, nibbles)
+		branch := node.NewBranchNode()
+		// if matched some nibbles, check if matches either all remaining nibbles
+		// or all leaf nibbles
+		if matched == leaf.Path.Len() {
+			branch.SetValue(leaf.Value)
+		}
 
-			// if all matched, update value even if the value are equal
-			if matched == len(nibbles) && matched == len(leaf.Path) {
-				newLeaf := node.NewLeafNodeFromNibbles(leaf.Path, value)
-				*root = newLeaf
-				return
-			}
+		if matched == path.Len() {
+			branch.SetValue(value)
+		}
 
+		if matched < leaf.Path.Len() {
+			// have dismatched
+			// L 01020304 hello
+			// + 010203   world
+
+			// 01020304, 0, 4
+			branchNibble, leafNibbles := leaf.Path.At(matched), leaf.Path.Slice(matched+1, leaf.Path.Len())
+			newLeaf := node.NewLeafNodeFromPath(leafNibbles, leaf.Value) // not :matched+1
+			branch.SetBranch(branchNibble, newLeaf)
+		}
+
+		if matched < path.Len() {
+			// L 01020304 hello
+			// + 010203040 world
+
+			// L 01020304 hello
+			// + 010203040506 world
+			branchNibble, leafNibbles := path.At(matched), path.Slice(matched+1, path.Len())
+			newLeaf := node.NewLeafNodeFromPath(leafNibbles, value)
+			branch.SetBranch(branchNibble, newLeaf)
+		}
+
+		// if there is matched nibbles, an extension node will be created
+		if matched > 0 {
+			// create an extension node for the shared nibbles
+			return node.NewExtensionNodeFromPath(leaf.Path.Slice(0, matched), branch), nil
+		}
+
+		// when there no matched nibble, there is no need to keep the extension node
+		return branch, nil
+	}
+
+	if branch, ok := n.(*node.BranchNode); ok {
+		if path.Len() == 0 {
+			branch = branch.Copy()
+			branch.SetValue(value)
+			return branch, nil
+		}
+
+		b, remaining := path.At(0), path.Slice(1, path.Len())
+		child, err := t.insert(branch.Branches[b], remaining, value)
+		if err != nil {
+			return nil, err
+		}
+		branch = branch.Copy()
+		branch.SetBranch(b, child)
+		return branch, nil
+	}
+
+	// E 01020304
+	// B 0 hello
+	// L 506 world
+	// + 010203 good
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		matched := ext.Path.MatchedLen(path)
+		if matched < ext.Path.Len() {
+			// E 01020304
+			// + 010203 good
+			extNibbles, branchNibble, extRemainingnibbles := ext.Path.Slice(0, matched), ext.Path.At(matched), ext.Path.Slice(matched+1, ext.Path.Len())
 			branch := node.NewBranchNode()
-			// if matched some nibbles, check if matches either all remaining nibbles
-			// or all leaf nibbles
-			if matched == len(leaf.Path) {
-				branch.SetValue(leaf.Value)
+			if extRemainingnibbles.Len() == 0 {
+				// E 0102030
+				// + 010203 good
+				branch.SetBranch(branchNibble, ext.Next)
+			} else {
+				// E 01020304
+				// + 010203 good
+				newExt := node.NewExtensionNodeFromPath(extRemainingnibbles, ext.Next)
+				branch.SetBranch(branchNibble, newExt)
 			}
 
-			if matched == len(nibbles) {
+			if matched < path.Len() {
+				nodeBranchNibble, nodeLeafNibbles := path.At(matched), path.Slice(matched+1, path.Len())
+				remainingLeaf := node.NewLeafNodeFromPath(nodeLeafNibbles, value)
+				branch.SetBranch(nodeBranchNibble, remainingLeaf)
+			} else if matched == path.Len() {
 				branch.SetValue(value)
+			} else {
+				return nil, fmt.Errorf("too many matched (%v > %v)", matched, path.Len())
 			}
 
-			// if there is matched nibbles, an extension node will be created
-			if matched > 0 {
-				// create an extension node for the shared nibbles
-				ext := node.NewExtensionNode(leaf.Path[:matched], branch)
-				*root = ext
-			} else {
-				// when there no matched nibble, there is no need to keep the extension node
-				*root = branch
+			// if there is no shared extension nibbles any more, then we don't need the extension node
+			// any more
+			// E 01020304
+			// + 1234 good
+			if extNibbles.Len() == 0 {
+				return branch, nil
 			}
+			// otherwise create a new extension node
+			return node.NewExtensionNodeFromPath(extNibbles, branch), nil
+		}
 
-			if matched < len(leaf.Path) {
-				// have dismatched
-				// L 01020304 hello
-				// + 010203   world
+		next, err := t.insert(ext.Next, path.Slice(matched, path.Len()), value)
+		if err != nil {
+			return nil, err
+		}
+		ext = ext.Copy()
+		ext.SetNext(next)
+		return ext, nil
+	}
 
-				// 01020304, 0, 4
-				branchNibble, leafNibbles := leaf.Path[matched], leaf.Path[matched+1:]
-				newLeaf := node.NewLeafNodeFromNibbles(leafNibbles, leaf.Value) // not :matched+1
-				branch.SetBranch(branchNibble, newLeaf)
-			}
+	return nil, unknownNode(n)
+}
 
-			if matched < len(nibbles) {
-				// L 01020304 hello
-				// + 010203040 world
+// Delete removes the key from the trie, and returns whether the key was found.
+// The trie is restored to the same shape it would have if the key had never been
+// put, so that the merkle root hash only depends on the remaining key-value pairs:
+// - A BranchNode left with a single child or only a value is collapsed.
+// - An ExtensionNode whose next node became a LeafNode or ExtensionNode is merged with it.
+// An error wrapping node.ErrMissingNode or node.ErrCorruptNode is returned
+// if a node on the path can't be loaded.
+func (t *Trie) Delete(key []byte) (bool, error) {
+	root, removed, err := t.remove(t.root, nibble.FromBytes(key))
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		t.root = root
+	}
+	return removed, nil
+}
 
-				// L 01020304 hello
-				// + 010203040506 world
-				branchNibble, leafNibbles := nibbles[matched], nibbles[matched+1:]
-				newLeaf := node.NewLeafNodeFromNibbles(leafNibbles, value)
-				branch.SetBranch(branchNibble, newLeaf)
-			}
+// remove deletes the remaining nibbles from the given node and returns
+// the node that should replace it. Like insert, it copies the nodes on the path
+// before updating them.
+func (t *Trie) remove(n node.Node, nibbles []nibble.Nibble) (node.Node, bool, error) {
+	if node.IsEmptyNode(n) {
+		return nil, false, nil
+	}
 
-			return
+	if hash, ok := n.(node.HashNode); ok {
+		resolved, err := t.resolve(hash)
+		if err != nil {
+			return nil, false, err
 		}
 
-		if branch, ok := (*root).(*node.BranchNode); ok {
-			if len(nibbles) == 0 {
-				branch.SetValue(value)
-				return
-			}
+		updated, removed, err := t.remove(resolved, nibbles)
+		if err != nil || !removed {
+			// keep the reference, since the node was not changed
+			return hash, false, err
+		}
+		return updated, true, nil
+	}
 
-			b, remaining := nibbles[0], nibbles[1:]
-			nibbles = remaining
-			root = &branch.Branches[b]
-			continue
+	if leaf, ok := n.(*node.LeafNode); ok {
+		matched := leaf.Path.PrefixMatchedLen(nibbles)
+		if matched != leaf.Path.Len() || matched != len(nibbles) {
+			return leaf, false, nil
 		}
+		return nil, true, nil
+	}
 
-		// E 01020304
-		// B 0 hello
-		// L 506 world
-		// + 010203 good
-		if ext, ok := (*root).(*node.ExtensionNode); ok {
-			matched := nibble.PrefixMatchedLen(ext.Path, nibbles)
-			if matched < len(ext.Path) {
-				// E 01020304
-				// + 010203 good
-				extNibbles, branchNibble, extRemainingnibbles := ext.Path[:matched], ext.Path[matched], ext.Path[matched+1:]
-				branch := node.NewBranchNode()
-				if len(extRemainingnibbles) == 0 {
-					// E 0102030
-					// + 010203 good
-					branch.SetBranch(branchNibble, ext.Next)
-				} else {
-					// E 01020304
-					// + 010203 good
-					newExt := node.NewExtensionNode(extRemainingnibbles, ext.Next)
-					branch.SetBranch(branchNibble, newExt)
-				}
-
-				if matched < len(nibbles) {
-					nodeBranchNibble, nodeLeafNibbles := nibbles[matched], nibbles[matched+1:]
-					remainingLeaf := node.NewLeafNodeFromNibbles(nodeLeafNibbles, value)
-					branch.SetBranch(nodeBranchNibble, remainingLeaf)
-				} else if matched == len(nibbles) {
-					branch.SetValue(value)
-				} else {
-					panic(fmt.Sprintf("too many matched (%v > %v)", matched, len(nibbles)))
-				}
-
-				// if there is no shared extension nibbles any more, then we don't need the extension node
-				// any more
-				// E 01020304
-				// + 1234 good
-				if len(extNibbles) == 0 {
-					*root = branch
-				} else {
-					// otherwise create a new extension node
-					*root = node.NewExtensionNode(extNibbles, branch)
-				}
-				return
+	if branch, ok := n.(*node.BranchNode); ok {
+		if len(nibbles) == 0 {
+			if !branch.HasValue() {
+				return branch, false, nil
 			}
+			branch = branch.Copy()
+			branch.RemoveValue()
+			collapsed, err := t.collapseBranch(branch)
+			return collapsed, err == nil, err
+		}
 
-			nibbles = nibbles[matched:]
-			root = &ext.Next
-			continue
+		b, remaining := nibbles[0], nibbles[1:]
+		child, removed, err := t.remove(branch.Branches[b], remaining)
+		if err != nil || !removed {
+			return branch, false, err
+		}
+
+		branch = branch.Copy()
+		if node.IsEmptyNode(child) {
+			branch.RemoveBranch(b)
+		} else {
+			branch.SetBranch(b, child)
+		}
+		collapsed, err := t.collapseBranch(branch)
+		return collapsed, err == nil, err
+	}
+
+	if ext, ok := n.(*node.ExtensionNode); ok {
+		matched := ext.Path.PrefixMatchedLen(nibbles)
+		if matched < ext.Path.Len() {
+			return ext, false, nil
 		}
 
-		panic("unknown type")
+		next, removed, err := t.remove(ext.Next, nibbles[matched:])
+		if err != nil || !removed {
+			return ext, false, err
+		}
+		return collapseExtension(ext.Path, next), true, nil
 	}
 
+	return nil, false, unknownNode(n)
 }
 
-// Prove returns the merkle proof for the given key, which is
-func (t *Trie) Prove(key []byte) (proof.Proof, bool) {
+// collapseBranch converts a branch node holding less than two entries
+// (children and value) into its equivalent leaf or extension node.
+func (t *Trie) collapseBranch(branch *node.BranchNode) (node.Node, error) {
+	children := 0
+	var only nibble.Nibble
+	for i, child := range branch.Branches {
+		if !node.IsEmptyNode(child) {
+			children++
+			only = nibble.Nibble(i)
+		}
+	}
+
+	if branch.HasValue() {
+		if children == 0 {
+			// B value: hello
+			// => L [] hello
+			return node.NewLeafNodeFromNibbles([]nibble.Nibble{}, branch.Value), nil
+		}
+		return branch, nil
+	}
+
+	if children != 1 {
+		return branch, nil
+	}
+
+	// the only child has to be loaded to know whether it can be merged
+	child := branch.Branches[only]
+	if hash, ok := child.(node.HashNode); ok {
+		resolved, err := t.resolve(hash)
+		if err != nil {
+			return nil, err
+		}
+		child = resolved
+	}
+
+	// B [3] -> N
+	// => E [3] -> N, which is then merged with N if possible
+	return collapseExtension(nibble.NewPath([]nibble.Nibble{only}), child), nil
+}
+
+// collapseExtension returns the node for the given path followed by the next node,
+// merging the path into the next node if it's a leaf or an extension node.
+func collapseExtension(path nibble.Path, next node.Node) node.Node {
+	if node.IsEmptyNode(next) {
+		return nil
+	}
+
+	if leaf, ok := next.(*node.LeafNode); ok {
+		// E 0102 -> L 03 hello
+		// => L 010203 hello
+		return node.NewLeafNodeFromPath(path.Concat(leaf.Path), leaf.Value)
+	}
+
+	if ext, ok := next.(*node.ExtensionNode); ok {
+		// E 0102 -> E 03 -> B
+		// => E 010203 -> B
+		return node.NewExtensionNodeFromPath(path.Concat(ext.Path), ext.Next)
+	}
+
+	return node.NewExtensionNodeFromPath(path, next)
+}
+
+// unknownNode returns the error for a node which is none of the trie node types.
+func unknownNode(n node.Node) error {
+	return fmt.Errorf("%w: unknown node type %T", node.ErrCorruptNode, n)
+}
+
+// concat joins two nibble paths into a new slice without sharing
+// the underlying array of either of them.
+func concat(a, b []nibble.Nibble) []nibble.Nibble {
+	joined := make([]nibble.Nibble, 0, len(a)+len(b))
+	joined = append(joined, a...)
+	return append(joined, b...)
+}
+
+// concatPath joins a nibble path and a packed path into a new slice of nibbles.
+func concatPath(a []nibble.Nibble, b nibble.Path) []nibble.Nibble {
+	joined := make([]nibble.Nibble, 0, len(a)+b.Len())
+	joined = append(joined, a...)
+	return b.AppendTo(joined)
+}
+
+// Prove returns the merkle proof for the given key, which contains the nodes on the path
+// from the root node to the key.
+// If the key is not in the trie, the proof contains the nodes on the path up to where
+// it diverges from the trie, which proves the absence of the key, and false is returned.
+func (t *Trie) Prove(key []byte) (proof.Proof, bool, error) {
 	proof := proof.NewProofDB()
+	found, err := t.prove(key, proof)
+	if err != nil {
+		return nil, false, err
+	}
+	return proof, found, nil
+}
+
+// ProveMany returns a single merkle proof for all the given keys, present or absent,
+// which contains the union of the nodes on their paths, so that the nodes shared by
+// the paths are only included once. It also returns whether each key was found.
+func (t *Trie) ProveMany(keys [][]byte) (proof.Proof, []bool, error) {
+	proof := proof.NewProofDB()
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		f, err := t.prove(key, proof)
+		if err != nil {
+			return nil, nil, err
+		}
+		found[i] = f
+	}
+	return proof, found, nil
+}
+
+// prove adds the nodes on the path of the key to the proof, and returns whether the key was found.
+func (t *Trie) prove(key []byte, proof *proof.ProofDB) (bool, error) {
 	root := t.root
 	nibbles := nibble.FromBytes(key)
 
 	for {
-		proof.Put(node.Hash(root), node.Serialize(root))
+		if hash, ok := root.(node.HashNode); ok {
+			resolved, err := t.resolve(hash)
+			if err != nil {
+				return false, err
+			}
+			root = resolved
+			continue
+		}
 
+		// the trie is empty, or the path leads to an empty branch
 		if node.IsEmptyNode(root) {
-			return nil, false
+			return false, nil
+		}
+
+		serialized, err := node.SerializeWith(t.hasher, root)
+		if err != nil {
+			return false, err
+		}
+		if err := proof.Put(node.HashWith(t.hasher, root), serialized); err != nil {
+			return false, err
 		}
 
 		if leaf, ok := root.(*node.LeafNode); ok {
-			matched := nibble.PrefixMatchedLen(leaf.Path, nibbles)
-			if matched != len(leaf.Path) || matched != len(nibbles) {
-				return nil, false
+			matched := leaf.Path.PrefixMatchedLen(nibbles)
+			// the path diverges from the leaf path
+			if matched != leaf.Path.Len() || matched != len(nibbles) {
+				return false, nil
 			}
 
-			return proof, true
+			return true, nil
 		}
 
 		if branch, ok := root.(*node.BranchNode); ok {
 			if len(nibbles) == 0 {
-				return proof, branch.HasValue()
+				return branch.HasValue(), nil
 			}
 
 			b, remaining := nibbles[0], nibbles[1:]
@@ -243,11 +510,11 @@ func (t *Trie) Prove(key []byte) (proof.Proof, bool) {
 		}
 
 		if ext, ok := root.(*node.ExtensionNode); ok {
-			matched := nibble.PrefixMatchedLen(ext.Path, nibbles)
+			matched := ext.Path.PrefixMatchedLen(nibbles)
 			// E 01020304
 			//   010203
-			if matched < len(ext.Path) {
-				return nil, false
+			if matched < ext.Path.Len() {
+				return false, nil
 			}
 
 			nibbles = nibbles[matched:]
@@ -255,12 +522,14 @@ func (t *Trie) Prove(key []byte) (proof.Proof, bool) {
 			continue
 		}
 
-		panic("not found")
+		return false, unknownNode(root)
 	}
 }
 
-// VerifyProof verify the proof for the given key under the given root hash using go-ethereum's VerifyProof implementation.
-// It returns the value for the key if the proof is valid, otherwise error will be returned
-func VerifyProof(rootHash []byte, key []byte, proof proof.Proof) (value []byte, err error) {
-	return trie.VerifyProof(common.BytesToHash(rootHash), key, proof)
+// VerifyProof verify the proof for the given key under the given root hash.
+// It returns the value for the key if the proof is valid, otherwise error will be returned.
+// If the proof shows the key is not in the trie, nil value and nil error are returned.
+func VerifyProof(rootHash []byte, key []byte, p proof.Proof) (value []byte, err error) {
+	value, _, err = proof.VerifyProof(rootHash, key, p)
+	return value, err
 }
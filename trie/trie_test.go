@@ -1,13 +1,18 @@
 package trie
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
+	"runtime"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/mpetrun5/merkle-patricia-trie/nibble"
 	"github.com/mpetrun5/merkle-patricia-trie/node"
 	"github.com/mpetrun5/merkle-patricia-trie/proof"
+	"github.com/mpetrun5/merkle-patricia-trie/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,14 +24,16 @@ func hexEqual(t *testing.T, hex string, bytes []byte) {
 func TestGetPut(t *testing.T) {
 	t.Run("should get nothing if key does not exist", func(t *testing.T) {
 		trie := NewTrie()
-		_, found := trie.Get([]byte("notexist"))
+		_, found, err := trie.Get([]byte("notexist"))
+		require.NoError(t, err)
 		require.Equal(t, false, found)
 	})
 
 	t.Run("should get value if key exist", func(t *testing.T) {
 		trie := NewTrie()
 		trie.Put([]byte{1, 2, 3, 4}, []byte("hello"))
-		val, found := trie.Get([]byte{1, 2, 3, 4})
+		val, found, err := trie.Get([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
 		require.Equal(t, true, found)
 		require.Equal(t, val, []byte("hello"))
 	})
@@ -35,7 +42,8 @@ func TestGetPut(t *testing.T) {
 		trie := NewTrie()
 		trie.Put([]byte{1, 2, 3, 4}, []byte("hello"))
 		trie.Put([]byte{1, 2, 3, 4}, []byte("world"))
-		val, found := trie.Get([]byte{1, 2, 3, 4})
+		val, found, err := trie.Get([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
 		require.Equal(t, true, found)
 		require.Equal(t, val, []byte("world"))
 	})
@@ -79,11 +87,13 @@ func TestPut2Pairs(t *testing.T) {
 	trie.Put([]byte{1, 2, 3, 4}, []byte("verb"))
 	trie.Put([]byte{1, 2, 3, 4, 5, 6}, []byte("coin"))
 
-	verb, ok := trie.Get([]byte{1, 2, 3, 4})
+	verb, ok, err := trie.Get([]byte{1, 2, 3, 4})
+	require.NoError(t, err)
 	require.True(t, ok)
 	require.Equal(t, []byte("verb"), verb)
 
-	coin, ok := trie.Get([]byte{1, 2, 3, 4, 5, 6})
+	coin, ok, err := trie.Get([]byte{1, 2, 3, 4, 5, 6})
+	require.NoError(t, err)
 	require.True(t, ok)
 	require.Equal(t, []byte("coin"), coin)
 
@@ -96,7 +106,9 @@ func TestPut2Pairs(t *testing.T) {
 	require.True(t, ok)
 
 	hexEqual(t, "c37ec985b7a88c2c62beb268750efe657c36a585beb435eb9f43b839846682ce", leaf.Hash())
-	hexEqual(t, "ddc882350684636f696e8080808080808080808080808080808476657262", branch.Serialize())
+	serialized, err := node.Serialize(branch)
+	require.NoError(t, err)
+	hexEqual(t, "ddc882350684636f696e8080808080808080808080808080808476657262", serialized)
 	hexEqual(t, "d757709f08f7a81da64a969200e59ff7e6cd6b06674c3f668ce151e84298aa79", branch.Hash())
 	hexEqual(t, "64d67c5318a714d08de6958c0e63a05522642f3f1087c6fd68a97837f203d359", ext.Hash())
 }
@@ -334,8 +346,12 @@ func TestMyTrie(t *testing.T) {
 	require.True(t, ok)
 	n1, ok := n0.Next.(*node.BranchNode)
 	require.True(t, ok)
-	fmt.Printf("n0 hash: %x, Serialized: %x\n", n0.Hash(), n0.Serialize())
-	fmt.Printf("n1 hash: %x, Serialized: %x\n", n1.Hash(), n1.Serialize())
+	s0, err := node.Serialize(n0)
+	require.NoError(t, err)
+	s1, err := node.Serialize(n1)
+	require.NoError(t, err)
+	fmt.Printf("n0 hash: %x, Serialized: %x\n", n0.Hash(), s0)
+	fmt.Printf("n1 hash: %x, Serialized: %x\n", n1.Hash(), s1)
 }
 
 func TestProveAndVerifyProof(t *testing.T) {
@@ -344,8 +360,58 @@ func TestProveAndVerifyProof(t *testing.T) {
 		tr.Put([]byte{1, 2, 3}, []byte("hello"))
 		tr.Put([]byte{1, 2, 3, 4, 5}, []byte("world"))
 		notExistKey := []byte{1, 2, 3, 4}
-		_, ok := tr.Prove(notExistKey)
+		_, ok, err := tr.Prove(notExistKey)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("should generate a proof of absence for non-exist key", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3}, []byte("hello"))
+		tr.Put([]byte{1, 2, 3, 4, 5}, []byte("world"))
+		tr.Put([]byte{1, 2, 4}, []byte("trie"))
+		rootHash := tr.Hash()
+
+		for key, nodes := range map[string]int{
+			"\x01\x02\x03\x04":     4, // diverges in the middle of the leaf path
+			"\x01\x02\x03\x04\x06": 4, // diverges at the end of the leaf path
+			"\x01\x02\x05":         2, // leads to an empty branch
+			"\x01\x03":             1, // diverges from the extension path
+			"\x01\x02":             1, // ends within the extension path
+		} {
+			key := []byte(key)
+			p, ok, err := tr.Prove(key)
+			require.NoError(t, err)
+			require.False(t, ok)
+			require.NoError(t, proof.VerifyAbsenceProof(rootHash, key, p), "key %x", key)
+			// the proof only holds the nodes on the path
+			require.Len(t, p.Serialize(), nodes, "key %x", key)
+
+			val, err := VerifyProof(rootHash, key, p)
+			require.NoError(t, err)
+			require.Nil(t, val)
+		}
+	})
+
+	t.Run("should generate a proof of absence for an empty trie", func(t *testing.T) {
+		tr := NewTrie()
+		p, ok, err := tr.Prove([]byte{1, 2, 3})
+		require.NoError(t, err)
 		require.False(t, ok)
+		require.NoError(t, proof.VerifyAbsenceProof(tr.Hash(), []byte{1, 2, 3}, p))
+		require.Empty(t, p.Serialize())
+	})
+
+	t.Run("should not accept a proof of inclusion as a proof of absence", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3}, []byte("hello"))
+		tr.Put([]byte{1, 2, 3, 4, 5}, []byte("world"))
+
+		p, ok, err := tr.Prove([]byte{1, 2, 3})
+		require.NoError(t, err)
+		require.True(t, ok)
+		err = proof.VerifyAbsenceProof(tr.Hash(), []byte{1, 2, 3}, p)
+		require.True(t, errors.Is(err, proof.ErrKeyExists), err)
 	})
 
 	t.Run("should generate a proof for an existing key, the proof can be verified with the merkle root hash", func(t *testing.T) {
@@ -354,7 +420,8 @@ func TestProveAndVerifyProof(t *testing.T) {
 		tr.Put([]byte{1, 2, 3, 4, 5}, []byte("world"))
 
 		key := []byte{1, 2, 3}
-		proof, ok := tr.Prove(key)
+		proof, ok, err := tr.Prove(key)
+		require.NoError(t, err)
 		require.True(t, ok)
 
 		rootHash := tr.Hash()
@@ -378,11 +445,216 @@ func TestProveAndVerifyProof(t *testing.T) {
 		// the proof was generated after the trie was updated
 		tr.Put([]byte{5, 6, 7}, []byte("trie"))
 		key := []byte{1, 2, 3}
-		proof, ok := tr.Prove(key)
+		proof, ok, err := tr.Prove(key)
+		require.NoError(t, err)
 		require.True(t, ok)
 
 		// should fail the verification since the merkle root hash doesn't match
-		_, err := VerifyProof(rootHash, key, proof)
+		_, err = VerifyProof(rootHash, key, proof)
 		require.Error(t, err)
 	})
 }
+
+func TestDelete(t *testing.T) {
+	t.Run("should return false if key does not exist", func(t *testing.T) {
+		tr := NewTrie()
+		removed, err := tr.Delete([]byte{1, 2, 3})
+		require.NoError(t, err)
+		require.False(t, removed)
+
+		tr.Put([]byte{1, 2, 3, 4}, []byte("hello"))
+		removed, err = tr.Delete([]byte{1, 2, 3})
+		require.NoError(t, err)
+		require.False(t, removed)
+		removed, err = tr.Delete([]byte{1, 2, 3, 4, 5})
+		require.NoError(t, err)
+		require.False(t, removed)
+	})
+
+	t.Run("should get nothing after the key was deleted", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3, 4}, []byte("hello"))
+		tr.Put([]byte{1, 2, 3, 5}, []byte("world"))
+
+		removed, err := tr.Delete([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		require.True(t, removed)
+		_, found, err := tr.Get([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		require.False(t, found)
+
+		val, found, err := tr.Get([]byte{1, 2, 3, 5})
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("world"), val)
+	})
+
+	t.Run("should be empty after all keys were deleted", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3, 4}, []byte("hello"))
+		tr.Put([]byte{1, 2, 3}, []byte("world"))
+
+		removed, err := tr.Delete([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		require.True(t, removed)
+		removed, err = tr.Delete([]byte{1, 2, 3})
+		require.NoError(t, err)
+		require.True(t, removed)
+		require.Equal(t, node.EmptyNodeHash, tr.Hash())
+	})
+
+	t.Run("should collapse a branch with only a value into a leaf", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3, 4}, []byte("hello"))
+		tr.Put([]byte{1, 2, 3}, []byte("world"))
+		removed, err := tr.Delete([]byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		expected := NewTrie()
+		expected.Put([]byte{1, 2, 3}, []byte("world"))
+		require.Equal(t, expected.Hash(), tr.Hash())
+		_, ok := tr.root.(*node.LeafNode)
+		require.True(t, ok)
+	})
+
+	t.Run("should merge extension paths after collapsing a branch", func(t *testing.T) {
+		tr := NewTrie()
+		tr.Put([]byte{1, 2, 3, 4}, []byte("hello1"))
+		tr.Put([]byte{1, 2, 3, 5}, []byte("hello2"))
+		tr.Put([]byte{1, 2, 5}, []byte("world"))
+		removed, err := tr.Delete([]byte{1, 2, 5})
+		require.NoError(t, err)
+		require.True(t, removed)
+
+		expected := NewTrie()
+		expected.Put([]byte{1, 2, 3, 4}, []byte("hello1"))
+		expected.Put([]byte{1, 2, 3, 5}, []byte("hello2"))
+		require.Equal(t, expected.Hash(), tr.Hash())
+
+		ext, ok := tr.root.(*node.ExtensionNode)
+		require.True(t, ok)
+		require.Equal(t, []nibble.Nibble{0, 1, 0, 2, 0, 3, 0}, ext.Path.Nibbles())
+	})
+}
+
+func TestDeleteMatchesEthTrie(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr := NewTrie()
+	mpt := new(trie.Trie)
+
+	keys := make([][]byte, 0, 500)
+	for i := 0; i < 500; i++ {
+		key := make([]byte, 1+rnd.Intn(4))
+		rnd.Read(key)
+		value := []byte(fmt.Sprintf("value%d", i))
+		keys = append(keys, key)
+		tr.Put(key, value)
+		mpt.Update(key, value)
+	}
+
+	for i, key := range keys {
+		if i%3 == 0 {
+			continue
+		}
+		tr.Delete(key)
+		mpt.Delete(key)
+		mptHash := mpt.Hash()
+		require.Equal(t, mptHash[:], tr.Hash(), "after deleting %x", key)
+	}
+}
+
+func TestProveMany(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr, keys, kv := randomTrie(rnd, 500)
+	rootHash := tr.Hash()
+
+	// some present keys and some absent keys
+	queried := [][]byte{keys[0], keys[42], keys[len(keys)-1], {0xff, 0xff, 0xff, 0xff, 0xff}, keys[100][:len(keys[100])-1]}
+	multi, found, err := tr.ProveMany(queried)
+	require.NoError(t, err)
+
+	t.Run("should be the union of the single proofs", func(t *testing.T) {
+		union := proof.NewProofDB()
+		for i, key := range queried {
+			single, ok, err := tr.Prove(key)
+			require.NoError(t, err)
+			require.Equal(t, ok, found[i])
+			for _, serialized := range single.Serialize() {
+				require.NoError(t, union.Put(crypto.Keccak256(serialized), serialized))
+			}
+		}
+		require.ElementsMatch(t, union.Serialize(), multi.Serialize())
+	})
+
+	t.Run("should verify all keys with one proof", func(t *testing.T) {
+		values, err := proof.VerifyMultiProof(rootHash, queried, multi)
+		require.NoError(t, err)
+		for i, key := range queried {
+			expected, ok := kv[string(key)]
+			require.Equal(t, ok, found[i])
+			if ok {
+				require.Equal(t, expected, values[i])
+			} else {
+				require.Nil(t, values[i])
+			}
+		}
+	})
+
+	t.Run("should fail if the proof of one key is missing", func(t *testing.T) {
+		_, err := proof.VerifyMultiProof(rootHash, append(queried, keys[300]), multi)
+		require.True(t, errors.Is(err, node.ErrMissingNode), err)
+	})
+}
+
+// BenchmarkPut reports the allocations of building a trie of hashed keys and the memory
+// the trie holds per key, and the allocations of loading the trie from the store.
+func BenchmarkPut(b *testing.B) {
+	keys := hashedKeys(10000)
+
+	// heapPerKey returns the memory held by the trie built by build, per key
+	heapPerKey := func(build func() *Trie) float64 {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tr := build()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		runtime.KeepAlive(tr)
+		return float64(after.HeapAlloc-before.HeapAlloc) / float64(len(keys))
+	}
+
+	put := func() *Trie {
+		tr := NewTrie()
+		for _, key := range keys {
+			tr.Put(key, key)
+		}
+		return tr
+	}
+
+	b.Run("put", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			put()
+		}
+		b.ReportMetric(heapPerKey(put), "heap-B/key")
+	})
+
+	db := storage.NewMemoryStore()
+	tr, _ := New(nil, db)
+	for _, key := range keys {
+		tr.Put(key, key)
+	}
+	root, _ := tr.Commit()
+
+	// iterating decodes each node of the trie from the store
+	b.Run("load", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			loaded, _ := New(root, db)
+			it := loaded.NewIterator(nil)
+			for it.Next() {
+			}
+		}
+	})
+}